@@ -0,0 +1,302 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/billing"
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/email"
+)
+
+const (
+	sessionCookie = "sd_session"
+	sessionTTL    = 30 * 24 * time.Hour
+	loginTokenTTL = 15 * time.Minute
+	// loginThrottle is the minimum gap between login emails to one address.
+	loginThrottle = time.Minute
+)
+
+// registerAccountRoutes serves subscriber self-service: magic-link login
+// and /api/me. Deleting an account cancels its subscription with payments.
+func registerAccountRoutes(mux *http.ServeMux, sqlDB *sql.DB, cfg config.Config, emailCfg *email.Config, payments billing.PaymentProvider) {
+	// POST /api/login {"email":...} emails a one-time sign-in link. The
+	// response is the same whether or not the address is subscribed, so it
+	// cannot be used to discover subscribers.
+	mux.HandleFunc("/api/login", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		var data struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+			return
+		}
+		addr := strings.ToLower(strings.TrimSpace(data.Email))
+		if addr == "" {
+			http.Error(w, `{"error":"email_required"}`, http.StatusBadRequest)
+			return
+		}
+		if err := sendLoginLink(sqlDB, cfg, emailCfg, addr); err != nil {
+			log.Printf("[account] login link for %s: %v", addr, err)
+		}
+		writeJSON(w, map[string]any{"success": true, "message": "If that address is subscribed, a sign-in link is on its way."})
+	})
+
+	// GET /api/login/verify?token= is the link in the email. It starts a
+	// session and redirects to the account page of the web app.
+	mux.HandleFunc("/api/login/verify", func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		id, err := db.ConsumeLoginToken(sqlDB, auth.Hash(token))
+		if err != nil {
+			if err.Error() != "not_found" {
+				log.Printf("[account] verify login error: %v", err)
+			}
+			http.Redirect(w, r, strings.TrimRight(cfg.AppURL, "/")+"/login?error=invalid_link", http.StatusSeeOther)
+			return
+		}
+		session, hash, err := auth.NewToken()
+		if err == nil {
+			err = db.CreateSession(sqlDB, id, hash, sessionTTL)
+		}
+		if err != nil {
+			log.Printf("[account] create session error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(w, sessionCookieFor(cfg, session, int(sessionTTL.Seconds())))
+		log.Printf("[account] subscriber %d signed in", id)
+		http.Redirect(w, r, strings.TrimRight(cfg.AppURL, "/")+"/account", http.StatusSeeOther)
+	})
+
+	mux.HandleFunc("/api/logout", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		if c, err := r.Cookie(sessionCookie); err == nil {
+			if err := db.DeleteSession(sqlDB, auth.Hash(c.Value)); err != nil {
+				log.Printf("[account] logout error: %v", err)
+			}
+		}
+		clearSessionCookie(w, cfg)
+		writeJSON(w, map[string]any{"success": true})
+	})
+
+	// /api/me shows (GET), edits (PUT) or deletes (DELETE) the signed-in
+	// subscriber's account.
+	mux.HandleFunc("/api/me", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		sub, ok := requireSession(w, r, sqlDB, cfg)
+		if !ok {
+			return
+		}
+		switch r.Method {
+		case "GET":
+			writeJSON(w, sub)
+		case "PUT":
+			var data struct {
+				FullName   *string `json:"fullName"`
+				Phone      *string `json:"phone"`
+				Address    *string `json:"address"`
+				City       *string `json:"city"`
+				Country    *string `json:"country"`
+				Timezone   *string `json:"timezone"`
+				Subscribed *bool   `json:"subscribed"`
+				// Preferences, if present, replace the stored ones.
+				Preferences *db.Preferences `json:"preferences"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			setIf(&sub.FullName, data.FullName)
+			setIf(&sub.Phone, data.Phone)
+			setIf(&sub.Address, data.Address)
+			setIf(&sub.City, data.City)
+			setIf(&sub.Country, data.Country)
+			setIf(&sub.Timezone, data.Timezone)
+			if sub.FullName == "" {
+				http.Error(w, `{"error":"missing_required_fields"}`, http.StatusBadRequest)
+				return
+			}
+			if _, err := time.LoadLocation(sub.Timezone); err != nil || sub.Timezone == "" {
+				http.Error(w, `{"error":"bad_timezone"}`, http.StatusBadRequest)
+				return
+			}
+			if data.Preferences != nil {
+				if !validPreferences(w, sqlDB, data.Preferences) {
+					return
+				}
+			}
+			err := db.UpdateSubscriberProfile(sqlDB, *sub)
+			if err == nil && data.Preferences != nil {
+				err = db.UpdatePreferences(sqlDB, sub.ID, *data.Preferences)
+			}
+			if err == nil && data.Subscribed != nil {
+				err = db.SetSubscribed(sqlDB, sub.ID, *data.Subscribed)
+			}
+			if err == nil {
+				sub, err = db.GetSubscriber(sqlDB, sub.ID)
+			}
+			if err != nil {
+				log.Printf("[account] update subscriber error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			log.Printf("[account] subscriber %d updated their details", sub.ID)
+			writeJSON(w, sub)
+		case "DELETE":
+			// A live paid subscription is canceled first so deleting the
+			// account also stops the billing. If it cannot be canceled,
+			// the account is kept and the subscriber can try again.
+			subscription, err := db.GetSubscriptionID(sqlDB, sub.ID)
+			if err != nil {
+				log.Printf("[account] subscription of %d: %v", sub.ID, err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			if subscription != "" && sub.Billing.Status != db.BillingCanceled {
+				if err := payments.Cancel(r.Context(), subscription); err != nil {
+					log.Printf("[account] cancel %s before deleting subscriber %d: %v", subscription, sub.ID, err)
+					http.Error(w, `{"error":"cancel_failed"}`, http.StatusInternalServerError)
+					return
+				}
+				log.Printf("[account] canceled %s for subscriber %d", subscription, sub.ID)
+			}
+			if err := db.DeleteSubscriber(sqlDB, sub.ID); err != nil {
+				log.Printf("[account] delete subscriber error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			clearSessionCookie(w, cfg)
+			log.Printf("[account] subscriber %d deleted their account", sub.ID)
+			writeJSON(w, map[string]any{"success": true})
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+// sendLoginLink emails a sign-in link to addr if it belongs to a
+// subscriber and no link was sent to it in the last loginThrottle.
+func sendLoginLink(sqlDB *sql.DB, cfg config.Config, emailCfg *email.Config, addr string) error {
+	sub, err := db.GetSubscriberByEmail(sqlDB, addr)
+	if err != nil {
+		if err.Error() == "not_found" {
+			return nil
+		}
+		return err
+	}
+	if recent, err := db.RecentLoginToken(sqlDB, sub.ID, loginThrottle); err != nil || recent {
+		return err
+	}
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		return err
+	}
+	if err := db.CreateLoginToken(sqlDB, sub.ID, hash, loginTokenTTL); err != nil {
+		return err
+	}
+	link := strings.TrimRight(cfg.BaseURL, "/") + "/api/login/verify?token=" + url.QueryEscape(token)
+	if emailCfg.SMTPUser == "" || emailCfg.SMTPPassword == "" {
+		log.Printf("[email] SMTP not configured - would send sign-in link to: %s", addr)
+		return nil
+	}
+	if err := email.SendLoginEmail(emailCfg, sub.FullName, sub.Email, link, loginTokenTTL); err != nil {
+		return err
+	}
+	log.Printf("[email] ✓ Sign-in link sent to: %s", addr)
+	return nil
+}
+
+// requireSession returns the subscriber signed in with the session cookie,
+// or writes a 401 response.
+func requireSession(w http.ResponseWriter, r *http.Request, sqlDB *sql.DB, cfg config.Config) (*db.Subscriber, bool) {
+	// The SameSite=None cookie is sent on requests from any site, so
+	// changes must come from the web app or the API itself.
+	if origin := r.Header.Get("Origin"); r.Method != "GET" && origin != "" && origin != appOrigin && origin != originOf(cfg.BaseURL) {
+		http.Error(w, `{"error":"bad_origin"}`, http.StatusForbidden)
+		return nil, false
+	}
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		http.Error(w, `{"error":"not_signed_in"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+	id, err := db.SessionSubscriber(sqlDB, auth.Hash(c.Value))
+	if err == nil {
+		var sub *db.Subscriber
+		if sub, err = db.GetSubscriber(sqlDB, id); err == nil {
+			return sub, true
+		}
+	}
+	if err.Error() != "not_found" {
+		log.Printf("[account] session lookup error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return nil, false
+	}
+	clearSessionCookie(w, cfg)
+	http.Error(w, `{"error":"not_signed_in"}`, http.StatusUnauthorized)
+	return nil, false
+}
+
+// validPreferences normalises p, writing a 400 response if it is invalid.
+func validPreferences(w http.ResponseWriter, sqlDB *sql.DB, p *db.Preferences) bool {
+	sources, err := db.ListSources(sqlDB, true)
+	if err != nil {
+		log.Printf("[account] list sources error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return false
+	}
+	if err := p.Validate(sources); err != nil {
+		writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "invalid_preferences", "message": err.Error()})
+		return false
+	}
+	return true
+}
+
+// setIf applies an optional field from a partial update.
+func setIf(field *string, v *string) {
+	if v != nil {
+		*field = strings.TrimSpace(*v)
+	}
+}
+
+func clearSessionCookie(w http.ResponseWriter, cfg config.Config) {
+	http.SetCookie(w, sessionCookieFor(cfg, "", -1))
+}
+
+// sessionCookieFor builds the session cookie. The web app at APP_URL calls
+// the API at BASE_URL with credentials; when they are different sites, as
+// on Render, the cookie must be SameSite=None, which browsers only accept
+// on Secure cookies. Plain-http development setups get SameSite=Lax.
+func sessionCookieFor(cfg config.Config, value string, maxAge int) *http.Cookie {
+	c := &http.Cookie{Name: sessionCookie, Value: value, Path: "/", MaxAge: maxAge, HttpOnly: true,
+		SameSite: http.SameSiteLaxMode}
+	if strings.HasPrefix(cfg.BaseURL, "https://") {
+		c.Secure, c.SameSite = true, http.SameSiteNoneMode
+	}
+	return c
+}
@@ -0,0 +1,555 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/calendar"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/summary"
+)
+
+// overrideResponse is a schedule override plus any pinned verses that have
+// since been deleted, so editors can see conflicts before the worker runs.
+type overrideResponse struct {
+	db.ScheduleOverride
+	MissingVerseIDs []int `json:"missing_verse_ids,omitempty"`
+}
+
+var sourceIDPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type ruleResponse struct {
+	calendar.Rule
+	Starts []string `json:"starts,omitempty"`
+}
+
+func registerAdminRoutes(mux *http.ServeMux, sqlDB *sql.DB) {
+	mux.HandleFunc("/api/admin/overrides", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+			for _, d := range []string{from, to} {
+				if d != "" && !validDate(d) {
+					http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+					return
+				}
+			}
+			list, err := db.ListScheduleOverrides(sqlDB, from, to)
+			if err != nil {
+				log.Printf("[admin] list overrides error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			out := make([]overrideResponse, 0, len(list))
+			for _, o := range list {
+				resp, err := withConflicts(sqlDB, o)
+				if err != nil {
+					log.Printf("[admin] list overrides error: %v", err)
+					http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+					return
+				}
+				out = append(out, resp)
+			}
+			writeJSON(w, out)
+		case "POST":
+			saveOverride(w, r, sqlDB, "")
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/admin/overrides/", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		date := r.URL.Path[len("/api/admin/overrides/"):]
+		if !validDate(date) {
+			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+			return
+		}
+		switch r.Method {
+		case "GET":
+			o, err := db.GetScheduleOverride(sqlDB, date)
+			if err != nil {
+				if err.Error() == "not_found" {
+					http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+					return
+				}
+				log.Printf("[admin] get override error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			resp, err := withConflicts(sqlDB, *o)
+			if err != nil {
+				log.Printf("[admin] get override error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, resp)
+		case "PUT":
+			saveOverride(w, r, sqlDB, date)
+		case "DELETE":
+			if err := db.DeleteScheduleOverride(sqlDB, date); err != nil {
+				if err.Error() == "not_found" {
+					http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+					return
+				}
+				log.Printf("[admin] delete override error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			log.Printf("[admin] removed schedule override for %s", date)
+			writeJSON(w, map[string]any{"success": true, "date": date})
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/admin/sources", protect(sqlDB, auth.Reader, auth.Admin, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			sources, err := db.ListSources(sqlDB, false)
+			if err != nil {
+				log.Printf("[admin] list sources error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, sources)
+		case "POST":
+			// Creates or replaces a registry entry. Verses for a new source
+			// are added with the same id in verses.source. When "enabled" is
+			// omitted, an existing source keeps its state and a new one is
+			// enabled, but a source can only be enabled once it has verses.
+			var req struct {
+				db.Source
+				Enabled *bool `json:"enabled"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			src := req.Source
+			if !sourceIDPattern.MatchString(src.ID) {
+				http.Error(w, `{"error":"bad_source_id"}`, http.StatusBadRequest)
+				return
+			}
+			src.Name = strings.TrimSpace(src.Name)
+			if src.Name == "" {
+				http.Error(w, `{"error":"name_required"}`, http.StatusBadRequest)
+				return
+			}
+			if src.CitationStyle == "" {
+				src.CitationStyle = "{ref}"
+			}
+			if req.Enabled != nil {
+				src.Enabled = *req.Enabled
+			} else {
+				existing, err := db.ListSources(sqlDB, false)
+				if err != nil {
+					log.Printf("[admin] list sources error: %v", err)
+					http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+					return
+				}
+				src.Enabled = true
+				for _, e := range existing {
+					if e.ID == src.ID {
+						src.Enabled = e.Enabled
+					}
+				}
+			}
+			if src.Enabled {
+				n, err := db.CountSourceVerses(sqlDB, src.ID)
+				if err != nil {
+					log.Printf("[admin] count source verses error: %v", err)
+					http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+					return
+				}
+				if n == 0 {
+					http.Error(w, `{"error":"source_has_no_verses"}`, http.StatusConflict)
+					return
+				}
+			}
+			if err := db.UpsertSource(sqlDB, src); err != nil {
+				log.Printf("[admin] save source error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			log.Printf("[admin] saved source %q (%s, position %d, enabled %v)", src.ID, src.Name, src.Position, src.Enabled)
+			writeJSON(w, src)
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/admin/calendar-rules", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			rules, err := db.ListCalendarRules(sqlDB)
+			if err != nil {
+				log.Printf("[admin] list calendar rules error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			// ?year= resolves each rule to the dates it starts on that year.
+			year, _ := strconv.Atoi(r.URL.Query().Get("year"))
+			out := make([]ruleResponse, 0, len(rules))
+			for _, rule := range rules {
+				resp := ruleResponse{Rule: rule}
+				if year > 0 {
+					resp.Starts = []string{}
+					for _, rd := range rule.Starts(year) {
+						resp.Starts = append(resp.Starts, calendar.TimeFromFixed(rd).Format("2006-01-02"))
+					}
+				}
+				out = append(out, resp)
+			}
+			writeJSON(w, out)
+		case "POST":
+			var rule calendar.Rule
+			if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			rule.Topic = strings.ToLower(strings.TrimSpace(rule.Topic))
+			if rule.Duration == 0 {
+				rule.Duration = 1
+			}
+			if err := rule.Validate(); err != nil {
+				writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "invalid_rule", "message": err.Error()})
+				return
+			}
+			id, err := db.CreateCalendarRule(sqlDB, rule)
+			if err != nil {
+				log.Printf("[admin] create calendar rule error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			rule.ID = id
+			log.Printf("[admin] added calendar rule %d %q -> %q", rule.ID, rule.Name, rule.Topic)
+			writeJSON(w, rule)
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/admin/calendar-rules/", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.Atoi(r.URL.Path[len("/api/admin/calendar-rules/"):])
+		if err != nil {
+			http.Error(w, `{"error":"bad_id"}`, http.StatusBadRequest)
+			return
+		}
+		if err := db.DeleteCalendarRule(sqlDB, id); err != nil {
+			if err.Error() == "not_found" {
+				http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+				return
+			}
+			log.Printf("[admin] delete calendar rule error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[admin] removed calendar rule %d", id)
+		writeJSON(w, map[string]any{"success": true, "id": id})
+	}))
+
+	mux.HandleFunc("/api/admin/summary-templates", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			templates, err := db.ListSummaryTemplates(sqlDB)
+			if err != nil {
+				log.Printf("[admin] list summary templates error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			if templates == nil {
+				templates = []summary.Template{}
+			}
+			writeJSON(w, templates)
+		case "POST":
+			t := summary.Template{Enabled: true}
+			if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			t.Topic = strings.ToLower(strings.TrimSpace(t.Topic))
+			if err := summary.Check(t.Body); err != nil {
+				writeJSONStatus(w, http.StatusBadRequest, map[string]string{"error": "invalid_template", "message": err.Error()})
+				return
+			}
+			id, err := db.CreateSummaryTemplate(sqlDB, t)
+			if err != nil {
+				log.Printf("[admin] create summary template error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			t.ID = id
+			log.Printf("[admin] added summary template %d for topic %q", t.ID, t.Topic)
+			writeJSON(w, t)
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/admin/summary-templates/", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		deleteByID(w, r, "/api/admin/summary-templates/", "summary template", func(id int) error {
+			return db.DeleteSummaryTemplate(sqlDB, id)
+		})
+	}))
+
+	mux.HandleFunc("/api/admin/topic-blurbs", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			blurbs, err := db.ListTopicBlurbs(sqlDB, strings.ToLower(r.URL.Query().Get("topic")))
+			if err != nil {
+				log.Printf("[admin] list topic blurbs error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			if blurbs == nil {
+				blurbs = []summary.Blurb{}
+			}
+			writeJSON(w, blurbs)
+		case "POST":
+			var b summary.Blurb
+			if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			b.Topic = strings.ToLower(strings.TrimSpace(b.Topic))
+			b.Body = strings.TrimSpace(b.Body)
+			if b.Topic == "" || b.Body == "" {
+				http.Error(w, `{"error":"topic_and_body_required"}`, http.StatusBadRequest)
+				return
+			}
+			id, err := db.CreateTopicBlurb(sqlDB, b)
+			if err != nil {
+				log.Printf("[admin] create topic blurb error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			b.ID = id
+			log.Printf("[admin] added blurb %d for topic %q", b.ID, b.Topic)
+			writeJSON(w, b)
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	mux.HandleFunc("/api/admin/topic-blurbs/", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		deleteByID(w, r, "/api/admin/topic-blurbs/", "topic blurb", func(id int) error {
+			return db.DeleteTopicBlurb(sqlDB, id)
+		})
+	}))
+}
+
+// handlePostRevisions serves the revision history under /api/post/{date}/:
+//
+//	GET  revisions                    list every revision, newest first
+//	GET  revisions/{n}                one revision
+//	POST revisions/{n}/restore        republish revision n as a new revision
+func handlePostRevisions(w http.ResponseWriter, r *http.Request, sqlDB *sql.DB, date, rest string) {
+	if r.Method == "OPTIONS" {
+		return
+	}
+	required := auth.Editor
+	if r.Method == "GET" {
+		required = auth.Reader
+	}
+	r, ok := authorize(w, r, sqlDB, required)
+	if !ok {
+		return
+	}
+	if !validDate(date) {
+		http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+		return
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	if parts[0] != "revisions" || len(parts) > 3 || (len(parts) == 3 && parts[2] != "restore") {
+		http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != "GET" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		revisions, err := db.ListPayloadRevisions(sqlDB, date)
+		if err != nil {
+			log.Printf("[admin] list revisions error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		if revisions == nil {
+			revisions = []db.PayloadRevision{}
+		}
+		writeJSON(w, revisions)
+		return
+	}
+
+	n, err := strconv.Atoi(parts[1])
+	if err != nil {
+		http.Error(w, `{"error":"bad_revision"}`, http.StatusBadRequest)
+		return
+	}
+	rev, err := db.GetPayloadRevision(sqlDB, date, n)
+	if err != nil {
+		if err.Error() == "not_found" {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		log.Printf("[admin] get revision error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != "GET" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, rev)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	var data struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if data.Reason == "" {
+		data.Reason = "restore of revision " + strconv.Itoa(n)
+	}
+	revision, err := db.SaveDailyPayload(sqlDB, rev.Payload, adminActor(r), data.Reason)
+	if err != nil {
+		log.Printf("[admin] restore revision error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[admin] %s restored %s revision %d as revision %d", adminActor(r), date, n, revision)
+	writeJSON(w, map[string]any{"success": true, "date": date, "restored": n, "revision": revision})
+}
+
+// saveOverride validates and upserts an override. pathDate is set for PUT
+// requests, where the date comes from the URL rather than the body.
+func saveOverride(w http.ResponseWriter, r *http.Request, sqlDB *sql.DB, pathDate string) {
+	var data struct {
+		Date     string `json:"date"`
+		Topic    string `json:"topic"`
+		VerseIDs []int  `json:"verse_ids"`
+		Note     string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+		return
+	}
+	if pathDate != "" {
+		data.Date = pathDate
+	}
+	data.Topic = strings.ToLower(strings.TrimSpace(data.Topic))
+	if !validDate(data.Date) {
+		http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+		return
+	}
+	if data.Topic == "" {
+		http.Error(w, `{"error":"topic_required"}`, http.StatusBadRequest)
+		return
+	}
+
+	ok, err := db.TopicExists(sqlDB, data.Topic)
+	if err != nil {
+		log.Printf("[admin] save override error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, `{"error":"unknown_topic"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Every pinned verse must exist, and at most one may be pinned per source.
+	seen := map[string]bool{}
+	for _, id := range data.VerseIDs {
+		v, err := db.GetVerseByID(sqlDB, id)
+		if err != nil {
+			if err.Error() == "not_found" {
+				writeJSONStatus(w, http.StatusBadRequest, map[string]any{"error": "unknown_verse", "verse_id": id})
+				return
+			}
+			log.Printf("[admin] save override error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		if seen[v.Source] {
+			writeJSONStatus(w, http.StatusBadRequest, map[string]any{"error": "duplicate_source", "source": v.Source})
+			return
+		}
+		seen[v.Source] = true
+	}
+
+	o := db.ScheduleOverride{Date: data.Date, Topic: data.Topic, VerseIDs: data.VerseIDs, Note: data.Note}
+	if err := db.UpsertScheduleOverride(sqlDB, o); err != nil {
+		log.Printf("[admin] save override error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[admin] pinned topic %q to %s (verses %v)", o.Topic, o.Date, o.VerseIDs)
+
+	saved, err := db.GetScheduleOverride(sqlDB, o.Date)
+	if err != nil {
+		log.Printf("[admin] save override error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, overrideResponse{ScheduleOverride: *saved})
+}
+
+// deleteByID handles DELETE {prefix}{id} for admin collections keyed by an
+// integer ID.
+func deleteByID(w http.ResponseWriter, r *http.Request, prefix, what string, del func(int) error) {
+	if r.Method != "DELETE" {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	id, err := strconv.Atoi(r.URL.Path[len(prefix):])
+	if err != nil {
+		http.Error(w, `{"error":"bad_id"}`, http.StatusBadRequest)
+		return
+	}
+	if err := del(id); err != nil {
+		if err.Error() == "not_found" {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		log.Printf("[admin] delete %s error: %v", what, err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[admin] removed %s %d", what, id)
+	writeJSON(w, map[string]any{"success": true, "id": id})
+}
+
+func withConflicts(sqlDB *sql.DB, o db.ScheduleOverride) (overrideResponse, error) {
+	missing, err := db.MissingVerseIDs(sqlDB, o.VerseIDs)
+	return overrideResponse{ScheduleOverride: o, MissingVerseIDs: missing}, err
+}
+
+func validDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/billing"
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/email"
+)
+
+// maxWebhookBody is the largest webhook payload accepted; provider events
+// are a few kilobytes.
+const maxWebhookBody = 64 << 10
+
+// newPaymentProvider builds the provider selected by PAYMENT_PROVIDER.
+func newPaymentProvider(cfg config.Config) (billing.PaymentProvider, error) {
+	switch cfg.PaymentProvider {
+	case "stripe":
+		return &billing.Stripe{
+			SecretKey:     cfg.StripeSecretKey,
+			PriceID:       cfg.StripePriceID,
+			PaymentLink:   cfg.StripePaymentLink,
+			WebhookSecret: cfg.StripeWebhookSecret,
+			Tolerance:     time.Duration(cfg.StripeWebhookTolerance) * time.Second,
+		}, nil
+	case "fake":
+		log.Printf("[billing] using the fake payment provider - no real payments are taken")
+		return billing.NewFake(cfg.BaseURL), nil
+	}
+	return nil, fmt.Errorf("unknown PAYMENT_PROVIDER %q (want stripe or fake)", cfg.PaymentProvider)
+}
+
+// checkoutRequest is a checkout for subscriber id that returns to the web
+// app.
+func checkoutRequest(cfg config.Config, id int, addr string) billing.CheckoutRequest {
+	app := strings.TrimRight(cfg.AppURL, "/")
+	return billing.CheckoutRequest{
+		SubscriberID: id,
+		Email:        addr,
+		SuccessURL:   app + "/payment-success?session_id={CHECKOUT_SESSION_ID}",
+		CancelURL:    app + "/subscribe?canceled=true",
+	}
+}
+
+// registerBillingRoutes serves the payment provider's webhook and the
+// subscriber's checkout and cancel routes.
+func registerBillingRoutes(mux *http.ServeMux, sqlDB *sql.DB, cfg config.Config, emailCfg *email.Config, payments billing.PaymentProvider) {
+	// POST /api/webhooks/{provider}. Providers retry any non-2xx
+	// response, so errors that a retry cannot fix (bad signature,
+	// malformed event) get a 400, failures calling back to the provider
+	// and server-side failures a 500.
+	mux.HandleFunc("/api/webhooks/"+payments.Name(), func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, `{"error":"body_too_large"}`, http.StatusRequestEntityTooLarge)
+			return
+		}
+		ev, err := payments.ParseWebhook(payload, r.Header)
+		var upstream *billing.UpstreamError
+		if errors.As(err, &upstream) {
+			log.Printf("[billing] ERROR completing %s webhook: %v", payments.Name(), err)
+			http.Error(w, `{"error":"provider_unavailable"}`, http.StatusInternalServerError)
+			return
+		}
+		if err != nil {
+			log.Printf("[billing] rejected %s webhook: %v", payments.Name(), err)
+			http.Error(w, `{"error":"bad_webhook"}`, http.StatusBadRequest)
+			return
+		}
+		if ev.Type == "" {
+			writeJSON(w, map[string]any{"received": true})
+			return
+		}
+
+		claimed, err := db.ClaimPaymentEvent(sqlDB, ev.ID, ev.Type)
+		if err != nil {
+			log.Printf("[billing] recording event %s: %v", ev.ID, err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		if !claimed {
+			log.Printf("[billing] %s %s already handled", ev.Type, ev.ID)
+			writeJSON(w, map[string]any{"received": true, "duplicate": true})
+			return
+		}
+		if err := handlePaymentEvent(sqlDB, cfg, emailCfg, ev); err != nil {
+			log.Printf("[billing] ERROR handling %s %s: %v", ev.Type, ev.ID, err)
+			if rerr := db.ReleasePaymentEvent(sqlDB, ev.ID); rerr != nil {
+				log.Printf("[billing] releasing event %s: %v", ev.ID, rerr)
+			}
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]any{"received": true})
+	})
+
+	// POST /api/billing/checkout starts a checkout for the signed-in
+	// subscriber and returns {"id", "url"}; the app redirects to url.
+	mux.HandleFunc("/api/billing/checkout", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		sub, ok := requireSession(w, r, sqlDB, cfg)
+		if !ok {
+			return
+		}
+		checkout, err := payments.CreateCheckout(r.Context(), checkoutRequest(cfg, sub.ID, sub.Email))
+		if err != nil {
+			log.Printf("[billing] checkout for subscriber %d: %v", sub.ID, err)
+			http.Error(w, `{"error":"checkout_failed"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, checkout)
+	})
+
+	// POST /api/billing/cancel cancels the signed-in subscriber's paid
+	// subscription. Billing changes when the provider confirms it.
+	mux.HandleFunc("/api/billing/cancel", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		sub, ok := requireSession(w, r, sqlDB, cfg)
+		if !ok {
+			return
+		}
+		subscription, err := db.GetSubscriptionID(sqlDB, sub.ID)
+		if err != nil {
+			log.Printf("[billing] subscription of %d: %v", sub.ID, err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		if subscription == "" {
+			http.Error(w, `{"error":"no_subscription"}`, http.StatusConflict)
+			return
+		}
+		if err := payments.Cancel(r.Context(), subscription); err != nil {
+			log.Printf("[billing] cancel %s for subscriber %d: %v", subscription, sub.ID, err)
+			http.Error(w, `{"error":"cancel_failed"}`, http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[billing] subscriber %d canceled %s", sub.ID, subscription)
+		writeJSON(w, map[string]any{"success": true})
+	})
+
+	if fake, ok := payments.(*billing.Fake); ok {
+		mux.Handle("/api/billing/fake/", fake.Handler())
+	}
+}
+
+// registerBillingAdminRoutes lets admins comp a subscription.
+func registerBillingAdminRoutes(mux *http.ServeMux, sqlDB *sql.DB) {
+	// PUT /api/admin/subscribers/{id}/comp {"until": RFC 3339 time or
+	// omitted for no end, "note": "..."} grants a complimentary
+	// subscription; DELETE removes it. Both return the subscriber.
+	mux.HandleFunc("/api/admin/subscribers/", protect(sqlDB, auth.Admin, auth.Admin, func(w http.ResponseWriter, r *http.Request) {
+		idStr, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/admin/subscribers/"), "/")
+		id, err := strconv.Atoi(idStr)
+		if err != nil || rest != "comp" {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		var data struct {
+			Until *time.Time `json:"until"`
+			Note  string     `json:"note"`
+		}
+		switch r.Method {
+		case "PUT":
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			if data.Until != nil && data.Until.Before(time.Now()) {
+				http.Error(w, `{"error":"until_in_past"}`, http.StatusBadRequest)
+				return
+			}
+			err = db.SetComp(sqlDB, adminActor(r), id, true, data.Until, data.Note)
+		case "DELETE":
+			err = db.SetComp(sqlDB, adminActor(r), id, false, nil, "")
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		if err != nil {
+			if err.Error() == "not_found" {
+				http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+				return
+			}
+			log.Printf("[admin] comp subscriber %d error: %v", id, err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[admin] %s set comp=%t for subscriber %d", adminActor(r), r.Method == "PUT", id)
+		sub, err := db.GetSubscriber(sqlDB, id)
+		if err != nil {
+			log.Printf("[admin] reload subscriber %d error: %v", id, err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, sub)
+	}))
+}
+
+// handlePaymentEvent applies a verified, first-seen event.
+func handlePaymentEvent(sqlDB *sql.DB, cfg config.Config, emailCfg *email.Config, ev *billing.Event) error {
+	switch ev.Type {
+	case billing.CheckoutCompleted:
+		return completeCheckout(sqlDB, cfg, emailCfg, ev)
+	case billing.PaymentSucceeded:
+		return setBillingStatus(sqlDB, ev, db.BillingActive, ev.PeriodEnd)
+	case billing.PaymentFailed:
+		return setBillingStatus(sqlDB, ev, db.BillingPastDue, time.Time{})
+	case billing.SubscriptionChanged:
+		return setBillingStatus(sqlDB, ev, ev.Status, ev.PeriodEnd)
+	case billing.SubscriptionEnded:
+		return setBillingStatus(sqlDB, ev, db.BillingCanceled, ev.PeriodEnd)
+	}
+	log.Printf("[billing] ignoring %s %s", ev.Type, ev.ID)
+	return nil
+}
+
+// completeCheckout links the provider's customer to the subscriber who
+// paid: the one the checkout was created for, else the one with the
+// payer's email. A payer who never signed up becomes a new subscriber.
+// When billing is required, this is when the welcome email is sent.
+func completeCheckout(sqlDB *sql.DB, cfg config.Config, emailCfg *email.Config, ev *billing.Event) error {
+	var sub *db.Subscriber
+	var err error
+	if ev.SubscriberID != 0 {
+		if sub, err = db.GetSubscriber(sqlDB, ev.SubscriberID); err != nil && err.Error() != "not_found" {
+			return err
+		}
+	}
+	addr := strings.ToLower(strings.TrimSpace(ev.Email))
+	if sub == nil && addr != "" {
+		if sub, err = db.GetSubscriberByEmail(sqlDB, addr); err != nil && err.Error() != "not_found" {
+			return err
+		}
+	}
+	if sub == nil {
+		if addr == "" {
+			return fmt.Errorf("checkout has no subscriber or email")
+		}
+		name := ev.Name
+		if name == "" {
+			name = addr
+		}
+		id, _, err := db.UpsertSubscriber(sqlDB, db.Subscriber{FullName: name, Email: addr})
+		if err != nil {
+			return err
+		}
+		log.Printf("[billing] created subscriber %d for %s at checkout", id, addr)
+		sub = &db.Subscriber{ID: id, FullName: name, Email: addr}
+	}
+
+	if err := db.LinkCustomer(sqlDB, sub.ID, ev.CustomerID, ev.SubscriptionID); err != nil {
+		return err
+	}
+	log.Printf("[billing] subscriber %d is customer %s", sub.ID, ev.CustomerID)
+	// The checkout only activates billing once paid for (or trialing),
+	// with the period it covers; otherwise the payment events do.
+	if ev.Status != "" && !ev.PeriodEnd.IsZero() {
+		if err := setBillingStatus(sqlDB, ev, ev.Status, ev.PeriodEnd); err != nil {
+			return err
+		}
+	}
+
+	if cfg.BillingRequired {
+		// The payment is recorded whatever happens to the email, so a
+		// failure here must not make the provider retry the event.
+		if emailCfg.SMTPUser == "" || emailCfg.SMTPPassword == "" {
+			log.Printf("[email] SMTP not configured - welcome email would be sent to: %s", sub.Email)
+		} else if err := email.SendWelcomeEmail(emailCfg, sub.FullName, sub.Email); err != nil {
+			log.Printf("[email] ERROR sending welcome email to %s: %v", sub.Email, err)
+		} else {
+			log.Printf("[email] ✓ Welcome email sent to: %s", sub.Email)
+		}
+	}
+	return nil
+}
+
+// setBillingStatus updates the subscriber's billing; a zero periodEnd keeps
+// the stored period end.
+func setBillingStatus(sqlDB *sql.DB, ev *billing.Event, status string, periodEnd time.Time) error {
+	var end *time.Time
+	if !periodEnd.IsZero() {
+		end = &periodEnd
+	}
+	matched, applied, err := db.SetBillingStatus(sqlDB, ev.CustomerID, ev.SubscriptionID, status, end, ev.Created)
+	if err != nil {
+		return err
+	}
+	switch {
+	case matched == 0 && ev.Type == billing.SubscriptionEnded:
+		// Deleting an account cancels its subscription after the
+		// subscriber is gone; there is nothing left to end.
+		log.Printf("[billing] no subscriber for ended subscription %q", ev.SubscriptionID)
+	case matched == 0:
+		// The checkout event has not arrived yet. Failing releases the
+		// event, so the provider redelivers it after the checkout.
+		return fmt.Errorf("no subscriber for customer %q subscription %q yet", ev.CustomerID, ev.SubscriptionID)
+	case applied == 0:
+		log.Printf("[billing] ignoring %s %s from %s: a newer event was already applied", ev.Type, ev.ID, ev.Created.Format(time.RFC3339))
+	default:
+		log.Printf("[billing] customer %s is now %s", ev.CustomerID, status)
+	}
+	return nil
+}
+
+// requireEntitlement guards premium routes when billing is required: the
+// request needs a session whose subscriber is entitled. It writes a 401 or
+// 402 response and returns false otherwise.
+func requireEntitlement(w http.ResponseWriter, r *http.Request, sqlDB *sql.DB, cfg config.Config) bool {
+	if !cfg.BillingRequired {
+		return true
+	}
+	sub, ok := requireSession(w, r, sqlDB, cfg)
+	if !ok {
+		return false
+	}
+	grace := time.Duration(cfg.BillingGraceDays) * 24 * time.Hour
+	if !sub.Billing.Entitled(time.Now(), grace) {
+		http.Error(w, `{"error":"payment_required"}`, http.StatusPaymentRequired)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/billing"
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/email"
+	"github.com/your/module/internal/stripe"
+)
+
+const webhookSecret = "whsec_test"
+
+// stripeAPIStub answers the Stripe API's subscription lookup, which a paid
+// checkout needs for its period end.
+type stripeAPIStub struct{}
+
+func (stripeAPIStub) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	if r.Method == "GET" && r.URL.Path == "/v1/subscriptions/sub_test_1" {
+		rec.WriteString(`{"id":"sub_test_1","customer":"cus_test_1","status":"active","current_period_end":1762592000}`)
+	} else {
+		rec.WriteHeader(http.StatusNotFound)
+		rec.WriteString(`{"error":{"message":"no stub for ` + r.Method + ` ` + r.URL.Path + `"}}`)
+	}
+	return rec.Result(), nil
+}
+
+// webhookServer serves the billing routes with Stripe as the provider.
+// sqlDB may be nil for requests rejected before the database is used.
+func webhookServer(t *testing.T, sqlDB *sql.DB) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	provider := &billing.Stripe{SecretKey: "sk_test", WebhookSecret: webhookSecret, Client: &http.Client{Transport: stripeAPIStub{}}}
+	registerBillingRoutes(mux, sqlDB, config.Config{}, &email.Config{}, provider)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func stripeFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "fixtures", "stripe", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// postWebhook posts payload to the Stripe webhook with the given
+// Stripe-Signature header and returns the status and decoded body.
+func postWebhook(t *testing.T, srv *httptest.Server, payload []byte, signature string) (int, map[string]any) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", srv.URL+"/api/webhooks/stripe", strings.NewReader(string(payload)))
+	if signature != "" {
+		req.Header.Set("Stripe-Signature", signature)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body map[string]any
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
+
+func TestWebhookRejectsUnsigned(t *testing.T) {
+	srv := webhookServer(t, nil)
+	payload := stripeFixture(t, stripe.InvoicePaid)
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name      string
+		payload   []byte
+		signature string
+	}{
+		{"no signature", payload, ""},
+		{"wrong secret", payload, stripe.Sign(payload, "whsec_other", now)},
+		{"tampered body", []byte(strings.Replace(string(payload), "999", "1", 1)), stripe.Sign(payload, webhookSecret, now)},
+		{"expired", payload, stripe.Sign(payload, webhookSecret, now.Add(-time.Hour))},
+		{"not json", []byte("nope"), stripe.Sign([]byte("nope"), webhookSecret, now)},
+	} {
+		if status, body := postWebhook(t, srv, tc.payload, tc.signature); status != http.StatusBadRequest || body["error"] != "bad_webhook" {
+			t.Errorf("%s: got %d %v, want 400 bad_webhook", tc.name, status, body)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/api/webhooks/stripe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET: got %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestWebhookIgnoresUnhandledEvents(t *testing.T) {
+	srv := webhookServer(t, nil)
+	payload := []byte(`{"id":"evt_test_refund","type":"charge.refunded","created":1760000000,"data":{"object":{}}}`)
+	status, body := postWebhook(t, srv, payload, stripe.Sign(payload, webhookSecret, time.Now()))
+	if status != http.StatusOK || body["received"] != true {
+		t.Errorf("got %d %v, want 200 received", status, body)
+	}
+}
+
+// TestWebhookRetriesWhenStripeIsDown checks that a paid checkout whose
+// subscription cannot be fetched is answered with a 500, so Stripe
+// retries it, rather than a 400 that marks it as rejected.
+func TestWebhookRetriesWhenStripeIsDown(t *testing.T) {
+	mux := http.NewServeMux()
+	down := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(http.StatusServiceUnavailable)
+		return rec.Result(), nil
+	})
+	provider := &billing.Stripe{SecretKey: "sk_test", WebhookSecret: webhookSecret, Client: &http.Client{Transport: down}}
+	registerBillingRoutes(mux, nil, config.Config{}, &email.Config{}, provider)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	payload := stripeFixture(t, stripe.CheckoutSessionCompleted)
+	status, body := postWebhook(t, srv, payload, stripe.Sign(payload, webhookSecret, time.Now()))
+	if status != http.StatusInternalServerError || body["error"] != "provider_unavailable" {
+		t.Errorf("got %d %v, want 500 provider_unavailable", status, body)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+// TestWebhookFixtures posts the signed fixtures through a subscription's
+// life and checks the subscriber's billing after each. It needs a
+// disposable Postgres database in TEST_DATABASE_URL.
+func TestWebhookFixtures(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	sqlDB := db.Connect(dsn)
+	defer sqlDB.Close()
+	const addr = "reader@example.com"
+	cleanup := func() {
+		sqlDB.Exec(`DELETE FROM subscribers WHERE email=$1`, addr)
+		sqlDB.Exec(`DELETE FROM stripe_events WHERE id LIKE 'evt_test_%'`)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+	srv := webhookServer(t, sqlDB)
+
+	periodEnd := func(sec int64) *time.Time {
+		end := time.Unix(sec, 0).UTC()
+		return &end
+	}
+	for _, step := range []struct {
+		fixture   string
+		status    string
+		periodEnd *time.Time
+	}{
+		{stripe.CheckoutSessionCompleted, db.BillingActive, periodEnd(1762592000)},
+		{stripe.SubscriptionCreated, db.BillingTrialing, periodEnd(1760604800)},
+		{stripe.InvoicePaid, db.BillingActive, periodEnd(1762592000)},
+		{stripe.InvoicePaymentFailed, db.BillingPastDue, periodEnd(1762592000)},
+		{stripe.SubscriptionDeleted, db.BillingCanceled, periodEnd(1765184000)},
+	} {
+		payload := stripeFixture(t, step.fixture)
+		if status, body := postWebhook(t, srv, payload, stripe.Sign(payload, webhookSecret, time.Now())); status != http.StatusOK {
+			t.Fatalf("%s: got %d %v, want 200", step.fixture, status, body)
+		}
+		sub, err := db.GetSubscriberByEmail(sqlDB, addr)
+		if err != nil {
+			t.Fatalf("%s: %v", step.fixture, err)
+		}
+		b := sub.Billing
+		if b.Status != step.status {
+			t.Errorf("%s: status %q, want %q", step.fixture, b.Status, step.status)
+		}
+		if b.PeriodEnd == nil || !b.PeriodEnd.Equal(*step.periodEnd) {
+			t.Errorf("%s: period end %v, want %v", step.fixture, b.PeriodEnd, step.periodEnd)
+		}
+		if step.status == db.BillingPastDue && b.PastDueSince == nil {
+			t.Errorf("%s: past_due_since not set", step.fixture)
+		}
+	}
+
+	// A redelivered event is acknowledged without being applied again.
+	payload := stripeFixture(t, stripe.InvoicePaid)
+	status, body := postWebhook(t, srv, payload, stripe.Sign(payload, webhookSecret, time.Now()))
+	if status != http.StatusOK || body["duplicate"] != true {
+		t.Errorf("redelivery: got %d %v, want 200 duplicate", status, body)
+	}
+	if sub, _ := db.GetSubscriberByEmail(sqlDB, addr); sub == nil || sub.Billing.Status != db.BillingCanceled {
+		t.Errorf("redelivery changed billing: %+v", sub)
+	}
+
+	// An update created before the deletion but delivered after it is
+	// acknowledged and ignored.
+	payload = []byte(strings.NewReplacer(
+		"evt_test_subscription_created_1", "evt_test_subscription_updated_late",
+		stripe.SubscriptionCreated, stripe.SubscriptionUpdated,
+	).Replace(string(stripeFixture(t, stripe.SubscriptionCreated))))
+	if status, body := postWebhook(t, srv, payload, stripe.Sign(payload, webhookSecret, time.Now())); status != http.StatusOK {
+		t.Errorf("late event: got %d %v, want 200", status, body)
+	}
+	if sub, _ := db.GetSubscriberByEmail(sqlDB, addr); sub == nil || sub.Billing.Status != db.BillingCanceled {
+		t.Errorf("late event changed billing: %+v", sub)
+	}
+}
+
+// TestWebhookInvoiceBeforeCheckout checks that an invoice delivered before
+// its checkout is refused, so Stripe retries it, and applied once the
+// checkout has linked the customer. It needs a disposable Postgres
+// database in TEST_DATABASE_URL.
+func TestWebhookInvoiceBeforeCheckout(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	sqlDB := db.Connect(dsn)
+	defer sqlDB.Close()
+	const addr = "reader@example.com"
+	cleanup := func() {
+		sqlDB.Exec(`DELETE FROM subscribers WHERE email=$1`, addr)
+		sqlDB.Exec(`DELETE FROM stripe_events WHERE id LIKE 'evt_test_%'`)
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+	srv := webhookServer(t, sqlDB)
+	post := func(fixture string) (int, map[string]any) {
+		payload := stripeFixture(t, fixture)
+		return postWebhook(t, srv, payload, stripe.Sign(payload, webhookSecret, time.Now()))
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, body := post(stripe.InvoicePaid); status != http.StatusInternalServerError {
+			t.Fatalf("invoice before checkout (attempt %d): got %d %v, want 500", i+1, status, body)
+		}
+	}
+	if status, body := post(stripe.CheckoutSessionCompleted); status != http.StatusOK {
+		t.Fatalf("checkout: got %d %v, want 200", status, body)
+	}
+	if status, body := post(stripe.InvoicePaid); status != http.StatusOK || body["duplicate"] == true {
+		t.Fatalf("invoice retry: got %d %v, want 200", status, body)
+	}
+	sub, err := db.GetSubscriberByEmail(sqlDB, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Unix(1762592000, 0).UTC()
+	if b := sub.Billing; b.Status != db.BillingActive || b.PeriodEnd == nil || !b.PeriodEnd.Equal(want) {
+		t.Errorf("billing = %s %v, want active until %v", b.Status, b.PeriodEnd, want)
+	}
+}
+
+// TestFakeProvider runs the fake provider's checkout, renewal and cancel
+// flows through the real webhook handler, then deletes the account, which
+// must cancel the subscription first. It needs a disposable Postgres
+// database in TEST_DATABASE_URL.
+func TestFakeProvider(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	sqlDB := db.Connect(dsn)
+	defer sqlDB.Close()
+	const addr = "fake-reader@example.com"
+	cleanup := func() { sqlDB.Exec(`DELETE FROM subscribers WHERE email=$1`, addr) }
+	cleanup()
+	t.Cleanup(cleanup)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	cfg := config.Config{BaseURL: srv.URL}
+	fake := billing.NewFake(srv.URL)
+	registerAccountRoutes(mux, sqlDB, cfg, &email.Config{}, fake)
+	registerBillingRoutes(mux, sqlDB, cfg, &email.Config{}, fake)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	id, _, err := db.UpsertSubscriber(sqlDB, db.Subscriber{Email: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	billingOf := func() db.Billing {
+		t.Helper()
+		sub, err := db.GetSubscriber(sqlDB, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sub.Billing
+	}
+	req := billing.CheckoutRequest{SubscriberID: id, Email: addr,
+		SuccessURL: "https://app.example/payment-success?session_id={CHECKOUT_SESSION_ID}", CancelURL: "https://app.example/subscribe?canceled=true"}
+	get := func(url string) *http.Response {
+		t.Helper()
+		resp, err := client.Get(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// A declined checkout returns to the cancel URL and changes nothing.
+	checkout, err := fake.CreateCheckout(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := get(checkout.URL); resp.StatusCode != http.StatusOK {
+		t.Errorf("checkout page: got %d, want 200", resp.StatusCode)
+	}
+	resp := get(checkout.URL + "&outcome=declined")
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != req.CancelURL {
+		t.Errorf("declined: got %d to %q, want 303 to %q", resp.StatusCode, resp.Header.Get("Location"), req.CancelURL)
+	}
+	if b := billingOf(); b.Status != db.BillingNone {
+		t.Errorf("after decline: status %q, want none", b.Status)
+	}
+	if resp := get(checkout.URL + "&outcome=paid"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("finished session: got %d, want 404", resp.StatusCode)
+	}
+
+	// A paid checkout makes billing active for one period.
+	if checkout, err = fake.CreateCheckout(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	resp = get(checkout.URL + "&outcome=paid")
+	wantLocation := "https://app.example/payment-success?session_id=" + checkout.ID
+	if resp.StatusCode != http.StatusSeeOther || resp.Header.Get("Location") != wantLocation {
+		t.Fatalf("paid: got %d to %q, want 303 to %q", resp.StatusCode, resp.Header.Get("Location"), wantLocation)
+	}
+	b := billingOf()
+	if b.Status != db.BillingActive || b.PeriodEnd == nil || b.PeriodEnd.Sub(start) < billing.FakePeriod-time.Minute {
+		t.Fatalf("after payment: %s until %v, want active for %v", b.Status, b.PeriodEnd, billing.FakePeriod)
+	}
+	firstEnd := *b.PeriodEnd
+	subscription, err := db.GetSubscriptionID(sqlDB, id)
+	if err != nil || subscription == "" {
+		t.Fatalf("subscription not linked: %q %v", subscription, err)
+	}
+
+	renew := func(outcome string) {
+		t.Helper()
+		resp, err := client.Post(srv.URL+"/api/billing/fake/renew?subscription="+subscription+"&outcome="+outcome, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("renew %s: got %d, want 200", outcome, resp.StatusCode)
+		}
+	}
+	renew("paid")
+	if b := billingOf(); b.Status != db.BillingActive || b.PeriodEnd == nil || !b.PeriodEnd.Equal(firstEnd.Add(billing.FakePeriod)) {
+		t.Errorf("after renewal: %s until %v, want active until %v", b.Status, b.PeriodEnd, firstEnd.Add(billing.FakePeriod))
+	}
+	renew("failed")
+	if b := billingOf(); b.Status != db.BillingPastDue {
+		t.Errorf("after failed renewal: status %q, want past_due", b.Status)
+	}
+
+	// Deleting the account cancels the subscription before removing it.
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateSession(sqlDB, id, hash, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	del, _ := http.NewRequest("DELETE", srv.URL+"/api/me", nil)
+	del.AddCookie(&http.Cookie{Name: sessionCookie, Value: token})
+	if resp, err = client.Do(del); err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE /api/me: got %d, want 200", resp.StatusCode)
+	}
+	if _, err := db.GetSubscriber(sqlDB, id); err == nil || err.Error() != "not_found" {
+		t.Errorf("subscriber still exists: %v", err)
+	}
+	if err := fake.Cancel(context.Background(), subscription); err == nil {
+		t.Errorf("subscription %s was not canceled", subscription)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"html/template"
+	"log"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/feed"
+	"github.com/your/module/internal/scripture"
+)
+
+// feedItems is how many days a feed holds.
+const feedItems = 30
+
+var feedTopic = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+var feedItemTemplate = template.Must(template.New("item").Parse(`<p><strong>{{.Topic}}</strong></p>
+{{range .Passages}}<h3>{{.Name}} ({{.Ref}})</h3>
+<p>{{.Text}}</p>
+{{end}}<h3>Common ground</h3>
+<p>{{.Summary}}</p>
+<p><a href="{{.Link}}">Read on Scripture Daily</a></p>
+`))
+
+func registerFeedRoutes(mux *http.ServeMux, sqlDB *sql.DB, cfg config.Config) {
+	mux.HandleFunc("/feed.rss", func(w http.ResponseWriter, r *http.Request) {
+		serveFeed(w, r, sqlDB, cfg, "", "rss")
+	})
+	mux.HandleFunc("/feed.atom", func(w http.ResponseWriter, r *http.Request) {
+		serveFeed(w, r, sqlDB, cfg, "", "atom")
+	})
+	// Per-topic feeds: /feed/{topic}.atom or /feed/{topic}.rss.
+	mux.HandleFunc("/feed/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/feed/")
+		format := strings.TrimPrefix(path.Ext(name), ".")
+		topic := strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+		if (format != "atom" && format != "rss") || !feedTopic.MatchString(topic) {
+			http.NotFound(w, r)
+			return
+		}
+		serveFeed(w, r, sqlDB, cfg, topic, format)
+	})
+}
+
+// serveFeed writes the feed of the latest payloads, of one topic unless
+// topic is "". Payloads dated after today are left out, and so is the
+// premium archive when billing is required. Last-Modified is the time the
+// newest entry changed, so readers polling with If-Modified-Since get a
+// 304 until a payload is published or edited.
+func serveFeed(w http.ResponseWriter, r *http.Request, sqlDB *sql.DB, cfg config.Config, topic, format string) {
+	setCORS(w, r)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	now := time.Now().UTC()
+	from := ""
+	if cfg.BillingRequired {
+		from = now.AddDate(0, 0, -cfg.ArchiveFreeDays).Format("2006-01-02")
+	}
+	payloads, err := db.ListRecentPayloads(sqlDB, from, now.Format("2006-01-02"), topic, feedItems)
+	if err != nil {
+		log.Printf("[api] feed error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sources, err := db.ListSources(sqlDB, true)
+	if err != nil {
+		log.Printf("[api] feed error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	app := strings.TrimRight(cfg.AppURL, "/")
+	f := feed.Feed{
+		Title:       "Scripture Daily",
+		Description: feedDescription(sources),
+		Link:        app + "/",
+		Self:        strings.TrimRight(cfg.BaseURL, "/") + r.URL.Path,
+	}
+	if topic != "" {
+		f.Title += ": " + strings.Title(topic)
+	}
+	for _, p := range payloads {
+		// As in emails, a payload with missing passages is not published.
+		if err := p.Validate(); err != nil {
+			log.Printf("[api] feed leaving out %s: %v", p.Date, err)
+			continue
+		}
+		item, err := feedItem(app, p)
+		if err != nil {
+			log.Printf("[api] feed item %s error: %v", p.Date, err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		f.Items = append(f.Items, item)
+		if p.UpdatedAt.After(f.Updated) {
+			f.Updated = p.UpdatedAt
+		}
+	}
+	if f.Updated.IsZero() {
+		// An empty feed has not changed since the epoch.
+		f.Updated = time.Unix(0, 0)
+	}
+
+	var body []byte
+	if format == "rss" {
+		body, err = feed.RSS(f)
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	} else {
+		body, err = feed.Atom(f)
+		w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	}
+	if err != nil {
+		log.Printf("[api] feed error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "public, max-age=900")
+	http.ServeContent(w, r, "", f.Updated, bytes.NewReader(body))
+}
+
+// feedDescription names the enabled sources: "Daily passages from Qur'an,
+// Torah, Bible and Human Design on a shared theme".
+func feedDescription(sources []db.Source) string {
+	names := make([]string, len(sources))
+	for i, s := range sources {
+		names[i] = s.Name
+	}
+	switch len(names) {
+	case 0:
+		return "Daily passages on a shared theme"
+	case 1:
+		return "Daily passages from " + names[0]
+	}
+	return "Daily passages from " + strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1] + " on a shared theme"
+}
+
+func feedItem(app string, p db.PublishedPayload) (feed.Item, error) {
+	link := app + "/post/" + p.Date
+	var content bytes.Buffer
+	err := feedItemTemplate.Execute(&content, struct {
+		Topic    string
+		Passages []scripture.Passage
+		Summary  string
+		Link     string
+	}{strings.Title(p.Area), p.Passages, p.Summary, link})
+	return feed.Item{
+		Title:     p.Date + ": " + strings.Title(p.Area),
+		Link:      link,
+		Published: p.CreatedAt,
+		Updated:   p.UpdatedAt,
+		Content:   content.String(),
+	}, err
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/scripture"
+)
+
+func TestFeedRoutesRejectBadPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	registerFeedRoutes(mux, nil, config.Config{})
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/feed/mercy.json", http.StatusNotFound},
+		{"GET", "/feed/mercy", http.StatusNotFound},
+		{"GET", "/feed/.rss", http.StatusNotFound},
+		{"GET", "/feed/mercy%20me.rss", http.StatusNotFound},
+		{"GET", "/feed/a/b.rss", http.StatusNotFound},
+		{"POST", "/feed.rss", http.StatusMethodNotAllowed},
+		{"DELETE", "/feed/mercy.atom", http.StatusMethodNotAllowed},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		if rec.Code != tc.want {
+			t.Errorf("%s %s: got %d, want %d", tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+}
+
+// TestFeeds checks which payloads the feeds list, per topic and with the
+// archive cutoff, and that unchanged feeds are answered with a 304. It
+// needs a disposable Postgres database in TEST_DATABASE_URL; it replaces
+// the payloads of the dates around today.
+func TestFeeds(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	sqlDB := db.Connect(dsn)
+	defer sqlDB.Close()
+
+	today := time.Now().UTC()
+	day := func(offset int) string { return today.AddDate(0, 0, offset).Format("2006-01-02") }
+	payloads := []struct {
+		offset   int
+		topic    string
+		complete bool
+	}{
+		{0, "feedtest", true},
+		{-1, "feedtest", false}, // left out: missing a passage
+		{-3, "feedtestother", true},
+		{-20, "feedtest", true}, // premium archive when billing is required
+		{1, "feedtest", true},   // not published yet
+	}
+	cleanup := func() {
+		for _, p := range payloads {
+			sqlDB.Exec(`DELETE FROM daily_payload_revisions WHERE date=$1`, day(p.offset))
+			sqlDB.Exec(`DELETE FROM daily_payloads WHERE date=$1`, day(p.offset))
+		}
+	}
+	cleanup()
+	t.Cleanup(cleanup)
+	save := func(offset int, topic string, complete bool) {
+		t.Helper()
+		text := "Be merciful."
+		if !complete {
+			text = ""
+		}
+		d := scripture.Daily{Date: day(offset), Area: topic, Summary: "Shared.", Passages: []scripture.Passage{
+			{Source: "bible", Name: "Bible", Ref: "Luke 6:36", Text: text, Citation: "Luke 6:36"},
+		}}
+		if _, err := db.SaveDailyPayload(sqlDB, d, "test", "feed test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range payloads {
+		save(p.offset, p.topic, p.complete)
+	}
+
+	cfg := config.Config{AppURL: "https://app.example", BaseURL: "https://api.example", BillingRequired: true, ArchiveFreeDays: 7}
+	get := func(cfg config.Config, path, ifModifiedSince string) *httptest.ResponseRecorder {
+		t.Helper()
+		mux := http.NewServeMux()
+		registerFeedRoutes(mux, sqlDB, cfg)
+		req := httptest.NewRequest("GET", path, nil)
+		if ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", ifModifiedSince)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	posts := func(body string) []string {
+		var out []string
+		for _, p := range payloads {
+			if strings.Contains(body, "https://app.example/post/"+day(p.offset)+"<") ||
+				strings.Contains(body, `href="https://app.example/post/`+day(p.offset)+`"`) {
+				out = append(out, day(p.offset))
+			}
+		}
+		return out
+	}
+
+	for _, tc := range []struct {
+		name        string
+		cfg         config.Config
+		path        string
+		contentType string
+		want        []string
+	}{
+		{"topic rss", cfg, "/feed/feedtest.rss", "application/rss+xml", []string{day(0)}},
+		{"topic atom", cfg, "/feed/FeedTest.atom", "application/atom+xml", []string{day(0)}},
+		{"other topic", cfg, "/feed/feedtestother.rss", "application/rss+xml", []string{day(-3)}},
+		{"no billing", config.Config{AppURL: cfg.AppURL, BaseURL: cfg.BaseURL}, "/feed/feedtest.atom", "application/atom+xml", []string{day(0), day(-20)}},
+	} {
+		rec := get(tc.cfg, tc.path, "")
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: got %d, want 200", tc.name, rec.Code)
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, tc.contentType) {
+			t.Errorf("%s: Content-Type %q, want %s", tc.name, ct, tc.contentType)
+		}
+		if got := posts(rec.Body.String()); strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: posts %v, want %v", tc.name, got, tc.want)
+		}
+	}
+	if body := get(cfg, "/feed/feedtest.atom", "").Body.String(); !strings.Contains(body, "<id>https://api.example/feed/feedtest.atom</id>") {
+		t.Errorf("atom id missing from:\n%s", body)
+	}
+
+	// Polling with the Last-Modified time gets a 304 until the payload
+	// changes.
+	lastModified := get(cfg, "/feed/feedtest.rss", "").Header().Get("Last-Modified")
+	if lastModified == "" {
+		t.Fatal("no Last-Modified")
+	}
+	if rec := get(cfg, "/feed/feedtest.rss", lastModified); rec.Code != http.StatusNotModified {
+		t.Errorf("unchanged: got %d, want 304", rec.Code)
+	}
+	time.Sleep(time.Second) // Last-Modified has one-second resolution
+	save(0, "feedtest", true)
+	rec := get(cfg, "/feed/feedtest.rss", lastModified)
+	if rec.Code != http.StatusOK {
+		t.Errorf("after an edit: got %d, want 200", rec.Code)
+	}
+	if body, _ := io.ReadAll(rec.Body); len(body) == 0 {
+		t.Error("after an edit: empty body")
+	}
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/your/module/internal/analytics"
+	"github.com/your/module/internal/auth"
 	"github.com/your/module/internal/config"
 	"github.com/your/module/internal/db"
 	"github.com/your/module/internal/email"
@@ -18,6 +26,7 @@ func main() {
 	sqlDB := db.Connect(cfg.DatabaseURL)
 	db.EnsureVisitorStats(sqlDB)
 	emailCfg := email.LoadConfig()
+	visits := &analytics.Tracker{DB: sqlDB}
 	log.Printf("[api] starting on :%s DB=%s", cfg.Port, cfg.DatabaseURL)
 
 	// Check if email is configured
@@ -27,13 +36,23 @@ func main() {
 		log.Printf("[email] SMTP not configured - emails will be logged only")
 	}
 
+	payments, err := newPaymentProvider(cfg)
+	if err != nil {
+		log.Fatalf("[billing] %v", err)
+	}
+
+	appOrigin = originOf(cfg.AppURL)
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200) })
 
 	mux.HandleFunc("/api/today", func(w http.ResponseWriter, r *http.Request) {
 		setCORS(w, r)
-		_ = db.IncrementVisitorCount(sqlDB)
-		today := time.Now().Format("2006-01-02")
+		today, err := requestToday(r)
+		if err != nil {
+			http.Error(w, `{"error":"bad_timezone"}`, http.StatusBadRequest)
+			return
+		}
+		visits.Record(r, "today")
 		payload, err := db.GetDailyPayloadDate(sqlDB, today)
 		if err != nil {
 			if errors.Is(err, os.ErrNotExist) || err.Error() == "not_found" {
@@ -50,11 +69,20 @@ func main() {
 
 	mux.HandleFunc("/api/post/", func(w http.ResponseWriter, r *http.Request) {
 		setCORS(w, r)
-		date := r.URL.Path[len("/api/post/"):]
+		date, rest, _ := strings.Cut(r.URL.Path[len("/api/post/"):], "/")
+		if rest != "" {
+			handlePostRevisions(w, r, sqlDB, date, rest)
+			return
+		}
 		if _, err := time.Parse("2006-01-02", date); err != nil {
 			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
 			return
 		}
+		// The recent archive is free; older days are premium.
+		cutoff := time.Now().UTC().AddDate(0, 0, -cfg.ArchiveFreeDays).Format("2006-01-02")
+		if date < cutoff && !requireEntitlement(w, r, sqlDB, cfg) {
+			return
+		}
 		payload, err := db.GetDailyPayloadDate(sqlDB, date)
 		if err != nil {
 			if err.Error() == "not_found" {
@@ -65,9 +93,22 @@ func main() {
 			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
 			return
 		}
+		visits.Record(r, "post")
 		writeJSON(w, payload)
 	})
 
+	// Enabled sources, for choosing traditions in subscriber preferences.
+	mux.HandleFunc("/api/sources", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		sources, err := db.ListSources(sqlDB, true)
+		if err != nil {
+			log.Printf("[api] /api/sources error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, sources)
+	})
+
 	mux.HandleFunc("/api/visitors", func(w http.ResponseWriter, r *http.Request) {
 		setCORS(w, r)
 		count, err := db.GetVisitorCount(sqlDB)
@@ -93,6 +134,10 @@ func main() {
 			Address  string `json:"address"`
 			City     string `json:"city"`
 			Country  string `json:"country"`
+			Timezone string `json:"timezone"`
+			// Preferences are optional; defaults are every source, daily,
+			// in English at DELIVERY_HOUR.
+			Preferences *db.Preferences `json:"preferences"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
@@ -106,6 +151,53 @@ func main() {
 			return
 		}
 
+		if data.Timezone == "" {
+			data.Timezone = "UTC"
+		}
+		if _, err := time.LoadLocation(data.Timezone); err != nil {
+			http.Error(w, `{"error":"bad_timezone"}`, http.StatusBadRequest)
+			return
+		}
+
+		var prefs db.Preferences
+		if data.Preferences != nil {
+			prefs = *data.Preferences
+			if !validPreferences(w, sqlDB, &prefs) {
+				return
+			}
+		}
+
+		data.Email = strings.ToLower(strings.TrimSpace(data.Email))
+		id, created, err := db.UpsertSubscriber(sqlDB, db.Subscriber{
+			FullName:    data.FullName,
+			Email:       data.Email,
+			Phone:       data.Phone,
+			Address:     data.Address,
+			City:        data.City,
+			Country:     data.Country,
+			Timezone:    data.Timezone,
+			Preferences: prefs,
+		})
+		if err != nil {
+			log.Printf("[subscription] ERROR storing subscriber: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		// Anyone can post any address, so signing up again does not touch
+		// the existing subscriber. Instead the address gets a sign-in link,
+		// from which its owner can change details or resubscribe.
+		if !created {
+			if err := sendLoginLink(sqlDB, cfg, emailCfg, data.Email); err != nil {
+				log.Printf("[subscription] sign-in link for existing subscriber %d: %v", id, err)
+			}
+			writeJSON(w, map[string]any{
+				"success": true,
+				"message": "This address is already registered. Check your email for a link to manage your subscription.",
+			})
+			return
+		}
+
 		// Log subscription
 		log.Printf("[subscription] New subscription:")
 		log.Printf("  Name: %s", data.FullName)
@@ -113,6 +205,23 @@ func main() {
 		log.Printf("  Phone: %s", data.Phone)
 		log.Printf("  Location: %s, %s, %s", data.Address, data.City, data.Country)
 
+		// With billing required the subscriber pays first; the welcome
+		// email is sent once the payment provider confirms checkout.
+		if cfg.BillingRequired {
+			checkout, err := payments.CreateCheckout(r.Context(), checkoutRequest(cfg, id, data.Email))
+			if err != nil {
+				log.Printf("[billing] checkout for subscriber %d: %v", id, err)
+				http.Error(w, `{"error":"checkout_failed"}`, http.StatusBadGateway)
+				return
+			}
+			writeJSON(w, map[string]any{
+				"success":     true,
+				"message":     "Complete payment to start your subscription.",
+				"checkoutUrl": checkout.URL,
+			})
+			return
+		}
+
 		// Prepare email content for response
 		emailContent := map[string]string{
 			"to":      data.Email,
@@ -146,8 +255,8 @@ func main() {
 	})
 
 	// Send daily scripture endpoint (for testing or manual sends)
-	mux.HandleFunc("/api/send-daily", func(w http.ResponseWriter, r *http.Request) {
-		setCORS(w, r)
+	// Sending mail to an arbitrary address needs a sender key.
+	mux.HandleFunc("/api/send-daily", protect(sqlDB, auth.Sender, auth.Sender, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != "POST" {
 			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
 			return
@@ -167,8 +276,12 @@ func main() {
 			return
 		}
 
-		// Get today's scripture
-		today := time.Now().Format("2006-01-02")
+		// Get today's scripture in the caller's time zone
+		today, err := requestToday(r)
+		if err != nil {
+			http.Error(w, `{"error":"bad_timezone"}`, http.StatusBadRequest)
+			return
+		}
 		payload, err := db.GetDailyPayloadDate(sqlDB, today)
 		if err != nil {
 			log.Printf("[api] /api/send-daily error: %v", err)
@@ -176,21 +289,10 @@ func main() {
 			return
 		}
 
-		// Convert db.Daily to email.Daily
-		emailPayload := &email.Daily{
-			Date:    payload.Date,
-			Area:    payload.Area,
-			Quran:   payload.Quran,
-			Torah:   payload.Torah,
-			Bible:   payload.Bible,
-			HD:      payload.HD,
-			Summary: payload.Summary,
-		}
-
 		// Send email if SMTP is configured
 		if emailCfg.SMTPUser != "" && emailCfg.SMTPPassword != "" {
 			log.Printf("[email] Sending daily scripture to: %s", data.Email)
-			err := email.SendDailyScriptureEmail(emailCfg, data.Email, emailPayload)
+			err := email.SendDailyScriptureEmail(emailCfg, data.Email, payload, email.Options{})
 			if err != nil {
 				log.Printf("[email] ERROR sending daily scripture: %v", err)
 				http.Error(w, `{"error":"email_send_failed"}`, http.StatusInternalServerError)
@@ -206,11 +308,59 @@ func main() {
 			"message": "Daily scripture sent successfully!",
 			"date":    today,
 		})
-	})
+	}))
+
+	registerAdminRoutes(mux, sqlDB)
+	registerVerseRoutes(mux, sqlDB)
+	registerAccountRoutes(mux, sqlDB, cfg, emailCfg, payments)
+	registerBillingRoutes(mux, sqlDB, cfg, emailCfg, payments)
+	registerBillingAdminRoutes(mux, sqlDB)
+	registerStatsRoutes(mux, sqlDB)
+	registerTrackingRoutes(mux, sqlDB, cfg)
+	registerReportRoutes(mux, sqlDB)
+	registerFeedRoutes(mux, sqlDB, cfg)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go visits.Run(ctx)
 
 	srv := &http.Server{Addr: ":" + cfg.Port, Handler: mux}
-	log.Printf("[api] listening on :%s", cfg.Port)
-	log.Fatal(srv.ListenAndServe())
+	go func() {
+		log.Printf("[api] listening on :%s", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	// Let requests in flight finish, then write the visits they counted.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[api] shutdown: %v", err)
+	}
+	if err := visits.Flush(); err != nil {
+		log.Printf("[api] flushing visitor counts: %v", err)
+	}
+	log.Printf("[api] stopped")
+}
+
+// requestToday returns today's date in the time zone named by the ?tz= query
+// parameter or the X-Timezone header (IANA names such as Asia/Kuala_Lumpur),
+// falling back to the server's local time zone.
+func requestToday(r *http.Request) (string, error) {
+	name := r.URL.Query().Get("tz")
+	if name == "" {
+		name = r.Header.Get("X-Timezone")
+	}
+	if name == "" {
+		return time.Now().Format("2006-01-02"), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return "", err
+	}
+	return time.Now().In(loc).Format("2006-01-02"), nil
 }
 
 func writeJSON(w http.ResponseWriter, v any) {
@@ -218,11 +368,35 @@ func writeJSON(w http.ResponseWriter, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// appOrigin is the web app's origin. Requests from it may send cookies,
+// which the session endpoints need; every other origin gets the public "*".
+var appOrigin string
+
 func setCORS(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	if origin := r.Header.Get("Origin"); origin != "" && origin == appOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Add("Vary", "Origin")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Timezone")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
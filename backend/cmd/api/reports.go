@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/db"
+)
+
+// report is a table that can be sent as JSON or CSV.
+type report struct {
+	rows   any
+	header []string
+	// records holds the CSV rows, in header order.
+	records [][]string
+}
+
+func registerReportRoutes(mux *http.ServeMux, sqlDB *sql.DB) {
+	// GET /api/admin/reports/{signups,countries,deliveries,engagement}?from=&to=,
+	// as CSV with ?format=csv.
+	// They hold subscriber data, so they need the admin role.
+	mux.HandleFunc("/api/admin/reports/", protect(sqlDB, auth.Admin, auth.Admin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/api/admin/reports/")
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			http.Error(w, `{"error":"bad_format"}`, http.StatusBadRequest)
+			return
+		}
+		to, err := dateParam(r, "to", time.Now().UTC())
+		if err != nil {
+			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+			return
+		}
+		from, err := dateParam(r, "from", to.AddDate(0, 0, 1-statsDefaultDays))
+		if err != nil {
+			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+			return
+		}
+		if from.After(to) || to.Sub(from) >= statsMaxDays*24*time.Hour {
+			http.Error(w, `{"error":"bad_range"}`, http.StatusBadRequest)
+			return
+		}
+
+		var rep *report
+		switch name {
+		case "signups":
+			rep, err = signupsReport(sqlDB, from, to)
+		case "countries":
+			rep, err = countriesReport(sqlDB)
+		case "deliveries":
+			rep, err = deliveriesReport(sqlDB, from, to)
+		case "engagement":
+			rep, err = engagementReport(sqlDB, from, to, r.URL.Query().Get("by") == "topic")
+		default:
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("[admin] %s report error: %v", name, err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		if format != "csv" {
+			writeJSON(w, rep.rows)
+			return
+		}
+		filename := fmt.Sprintf("%s-%s-%s.csv", name, from.Format("2006-01-02"), to.Format("2006-01-02"))
+		if name == "countries" {
+			filename = fmt.Sprintf("countries-%s.csv", time.Now().UTC().Format("2006-01-02"))
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		cw := csv.NewWriter(w)
+		_ = cw.Write(rep.header)
+		for _, rec := range rep.records {
+			for i := range rec {
+				rec[i] = csvSafe(rec[i])
+			}
+			_ = cw.Write(rec)
+		}
+		cw.Flush()
+	}))
+}
+
+func signupsReport(sqlDB *sql.DB, from, to time.Time) (*report, error) {
+	days, err := db.ListSignupDays(sqlDB, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	rep := &report{rows: days,
+		header: []string{"date", "signups", "signed_in", "signed_in_rate", "unsubscribes", "bounces"}}
+	for _, d := range days {
+		rep.records = append(rep.records, []string{d.Date, itoa(d.Signups), itoa(d.SignedIn),
+			ftoa(d.SignedInRate), itoa(d.Unsubscribes), itoa(d.Bounces)})
+	}
+	if days == nil {
+		rep.rows = []db.SignupDay{}
+	}
+	return rep, nil
+}
+
+func countriesReport(sqlDB *sql.DB) (*report, error) {
+	counts, err := db.ListActiveByCountry(sqlDB)
+	if err != nil {
+		return nil, err
+	}
+	rep := &report{rows: counts, header: []string{"country", "active"}}
+	for _, c := range counts {
+		rep.records = append(rep.records, []string{c.Country, itoa(c.Active)})
+	}
+	if counts == nil {
+		rep.rows = []db.CountryCount{}
+	}
+	return rep, nil
+}
+
+func deliveriesReport(sqlDB *sql.DB, from, to time.Time) (*report, error) {
+	runs, err := db.ListDeliveryRuns(sqlDB, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	rep := &report{rows: runs,
+		header: []string{"id", "kind", "started_at", "finished_at", "sent", "failed", "bounced", "success_rate"}}
+	for _, run := range runs {
+		rep.records = append(rep.records, []string{itoa(run.ID), run.Kind,
+			run.StartedAt.UTC().Format(time.RFC3339), run.FinishedAt.UTC().Format(time.RFC3339),
+			itoa(run.Sent), itoa(run.Failed), itoa(run.Bounced), ftoa(run.SuccessRate)})
+	}
+	if runs == nil {
+		rep.rows = []db.DeliveryRun{}
+	}
+	return rep, nil
+}
+
+func engagementReport(sqlDB *sql.DB, from, to time.Time, byTopic bool) (*report, error) {
+	rows, err := db.ListEngagement(sqlDB, from.Format("2006-01-02"), to.Format("2006-01-02"), byTopic)
+	if err != nil {
+		return nil, err
+	}
+	rep := &report{rows: rows,
+		header: []string{"date", "topic", "sent", "opened", "clicked", "opens", "clicks", "open_rate", "click_rate"}}
+	for _, e := range rows {
+		rep.records = append(rep.records, []string{e.Date, e.Topic, itoa(e.Sent), itoa(e.Opened), itoa(e.Clicked),
+			itoa(e.Opens), itoa(e.Clicks), ftoa(e.OpenRate), ftoa(e.ClickRate)})
+	}
+	if rows == nil {
+		rep.rows = []db.Engagement{}
+	}
+	return rep, nil
+}
+
+// csvSafe keeps spreadsheets from reading a cell, such as a country typed
+// in at signup, as a formula: a cell starting with =, +, -, @, a tab or a
+// carriage return gets a leading apostrophe.
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func itoa(n int) string { return strconv.Itoa(n) }
+
+func ftoa(f float64) string { return strconv.FormatFloat(f, 'f', 4, 64) }
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestCSVSafe(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                  "",
+		"MY":                "MY",
+		"2026-10-19":        "2026-10-19",
+		"0.5000":            "0.5000",
+		"=HYPERLINK(\"x\")": "'=HYPERLINK(\"x\")",
+		"+1":                "'+1",
+		"-1+2":              "'-1+2",
+		"@SUM(A1)":          "'@SUM(A1)",
+		"\t=1":              "'\t=1",
+		"\r=1":              "'\r=1",
+	} {
+		if got := csvSafe(in); got != want {
+			t.Errorf("csvSafe(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
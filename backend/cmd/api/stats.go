@@ -0,0 +1,85 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/your/module/internal/db"
+)
+
+const (
+	// statsDefaultDays is the range of /api/stats without from.
+	statsDefaultDays = 30
+	// statsMaxDays bounds the range of one /api/stats request.
+	statsMaxDays = 366
+)
+
+type endpointTotals struct {
+	Hits int `json:"hits"`
+	// Uniques adds up each day's unique visitors, so someone who came on
+	// two days counts twice.
+	Uniques int `json:"unique_visitors"`
+}
+
+func registerStatsRoutes(mux *http.ServeMux, sqlDB *sql.DB) {
+	// Daily views and unique visitors per endpoint, both dates inclusive.
+	mux.HandleFunc("/api/stats", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		if r.Method != "GET" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		to, err := dateParam(r, "to", time.Now().UTC())
+		if err != nil {
+			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+			return
+		}
+		from, err := dateParam(r, "from", to.AddDate(0, 0, 1-statsDefaultDays))
+		if err != nil {
+			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+			return
+		}
+		if from.After(to) || to.Sub(from) >= statsMaxDays*24*time.Hour {
+			http.Error(w, `{"error":"bad_range"}`, http.StatusBadRequest)
+			return
+		}
+
+		days, err := db.ListVisitorDays(sqlDB, from.Format("2006-01-02"), to.Format("2006-01-02"))
+		if err != nil {
+			log.Printf("[api] /api/stats error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		totals := map[string]endpointTotals{}
+		for _, d := range days {
+			t := totals[d.Endpoint]
+			t.Hits += d.Hits
+			t.Uniques += d.Uniques
+			totals[d.Endpoint] = t
+		}
+		if days == nil {
+			days = []db.VisitorDay{}
+		}
+		writeJSON(w, map[string]any{
+			"from":   from.Format("2006-01-02"),
+			"to":     to.Format("2006-01-02"),
+			"days":   days,
+			"totals": totals,
+		})
+	})
+}
+
+// dateParam parses the YYYY-MM-DD query parameter name, or returns def if
+// it is absent.
+func dateParam(r *http.Request, name string, def time.Time) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/analytics"
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+)
+
+// pixel is a transparent 1x1 GIF.
+var pixel = []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00!\xf9\x04\x01\x00\x00\x00\x00,\x00\x00\x00\x00\x01\x00\x01\x00\x00\x02\x02D\x01\x00;")
+
+func registerTrackingRoutes(mux *http.ServeMux, sqlDB *sql.DB, cfg config.Config) {
+	// The open pixel of a daily email. It is served whatever happens, so
+	// the email never shows a broken image.
+	mux.HandleFunc("/t/o/", func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/t/o/")
+		if !analytics.IsBot(r.UserAgent()) {
+			if err := db.RecordEmailOpen(sqlDB, token); err != nil {
+				log.Printf("[api] recording email open: %v", err)
+			}
+		}
+		w.Header().Set("Content-Type", "image/gif")
+		w.Header().Set("Cache-Control", "no-store, max-age=0")
+		_, _ = w.Write(pixel)
+	})
+
+	// A tracked link, /t/c/{token}.{n}: records the click and redirects to
+	// the link's URL. Unknown links go to the web app.
+	mux.HandleFunc("/t/c/", func(w http.ResponseWriter, r *http.Request) {
+		token, num, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/t/c/"), ".")
+		n, err := strconv.Atoi(num)
+		if err != nil {
+			http.Redirect(w, r, cfg.AppURL, http.StatusFound)
+			return
+		}
+		var url string
+		if analytics.IsBot(r.UserAgent()) {
+			// Link scanners follow the redirect without counting as a click.
+			url, err = db.GetEmailLink(sqlDB, token, n)
+		} else {
+			url, err = db.RecordEmailClick(sqlDB, token, n)
+		}
+		if err != nil {
+			if err.Error() != "not_found" {
+				log.Printf("[api] recording email click: %v", err)
+			}
+			if url == "" {
+				url = cfg.AppURL
+			}
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, url, http.StatusFound)
+	})
+
+	// The one-click opt-out in tracked emails, /t/optout/{subscriber}.{sig}:
+	// sets no_tracking without signing in, as the signature proves the
+	// link came from an email to that subscriber. GET is enough so the
+	// link works from any mail client.
+	mux.HandleFunc("/t/optout/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "POST" {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		idStr, sig, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/t/optout/"), ".")
+		id, err := strconv.Atoi(idStr)
+		if err != nil || !auth.ValidOptOutSignature(cfg.TrackingSecret, id, sig) {
+			http.Error(w, "This link is not valid. You can turn tracking off on your account page.", http.StatusBadRequest)
+			return
+		}
+		if err := db.OptOutOfTracking(sqlDB, id); err != nil && err.Error() != "not_found" {
+			log.Printf("[api] tracking opt-out of subscriber %d: %v", id, err)
+			http.Error(w, "Something went wrong. Please try again.", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[api] subscriber %d opted out of tracking by email link", id)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprint(w, `<!doctype html><title>Tracking turned off</title>
+<h1>Tracking turned off</h1>
+<p>We will no longer record when you open Scripture Daily emails or click their links, and what we recorded so far has been deleted.</p>
+`)
+	})
+
+	// Opens and clicks of tracked daily emails, per date or with
+	// ?by=topic per topic.
+	mux.HandleFunc("/api/admin/engagement", protect(sqlDB, auth.Admin, auth.Admin, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		to, err := dateParam(r, "to", time.Now().UTC())
+		if err != nil {
+			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+			return
+		}
+		from, err := dateParam(r, "from", to.AddDate(0, 0, 1-statsDefaultDays))
+		if err != nil {
+			http.Error(w, `{"error":"bad_date"}`, http.StatusBadRequest)
+			return
+		}
+		by := r.URL.Query().Get("by")
+		if by != "" && by != "date" && by != "topic" {
+			http.Error(w, `{"error":"bad_by"}`, http.StatusBadRequest)
+			return
+		}
+		rows, err := db.ListEngagement(sqlDB, from.Format("2006-01-02"), to.Format("2006-01-02"), by == "topic")
+		if err != nil {
+			log.Printf("[admin] engagement report error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		if rows == nil {
+			rows = []db.Engagement{}
+		}
+		writeJSON(w, rows)
+	}))
+}
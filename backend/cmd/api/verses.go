@@ -0,0 +1,486 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/scripture"
+	"github.com/your/module/internal/similarity"
+)
+
+// verseIndexMaxAge bounds how long a similarity index built by this
+// replica is used; edits made here invalidate it at once.
+const verseIndexMaxAge = 10 * time.Minute
+
+func registerVerseRoutes(mux *http.ServeMux, sqlDB *sql.DB) {
+	index := &similarity.Cache{
+		Load:   func() ([]scripture.Verse, error) { return db.ListVerses(sqlDB, db.VerseFilter{}) },
+		MaxAge: verseIndexMaxAge,
+	}
+
+	// GET /api/verses/{id}/similar?limit=&source= lists the verses whose
+	// text is closest to the given verse.
+	mux.HandleFunc("/api/verses/", func(w http.ResponseWriter, r *http.Request) {
+		setCORS(w, r)
+		if r.Method == "OPTIONS" {
+			return
+		}
+		id, rest, _ := strings.Cut(r.URL.Path[len("/api/verses/"):], "/")
+		if rest != "similar" {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		if r.Method != "GET" {
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		verse, idx, ok := verseIndex(w, sqlDB, index, id)
+		if !ok {
+			return
+		}
+		limit := queryLimit(r, 10, 50)
+		source := r.URL.Query().Get("source")
+		matches := []similarity.Match{}
+		for _, m := range idx.Similar(*verse, 0) {
+			if source != "" && m.Verse.Source != source {
+				continue
+			}
+			matches = append(matches, m)
+			if len(matches) == limit {
+				break
+			}
+		}
+		writeJSON(w, map[string]any{"verse": verse, "similar": matches})
+	})
+
+	mux.HandleFunc("/api/admin/verses", protect(sqlDB, auth.Reader, auth.Editor, invalidating(index, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			q := r.URL.Query()
+			offset, _ := strconv.Atoi(q.Get("offset"))
+			if offset < 0 {
+				offset = 0
+			}
+			verses, err := db.ListVerses(sqlDB, db.VerseFilter{
+				Source:   q.Get("source"),
+				Topic:    strings.ToLower(q.Get("topic")),
+				Query:    q.Get("q"),
+				Untagged: q.Get("untagged") == "1" || q.Get("untagged") == "true",
+				Limit:    queryLimit(r, 100, 500),
+				Offset:   offset,
+			})
+			if err != nil {
+				log.Printf("[admin] list verses error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			if verses == nil {
+				verses = []scripture.Verse{}
+			}
+			writeJSON(w, verses)
+		case "POST":
+			saveVerse(w, r, sqlDB, 0)
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// /api/admin/verses/bulk-tag, /api/admin/verses/{id} and
+	// /api/admin/verses/{id}/suggest-topics.
+	mux.HandleFunc("/api/admin/verses/", protect(sqlDB, auth.Reader, auth.Editor, invalidating(index, func(w http.ResponseWriter, r *http.Request) {
+		rawID, rest, _ := strings.Cut(r.URL.Path[len("/api/admin/verses/"):], "/")
+		switch {
+		case rawID == "bulk-tag" && rest == "":
+			if r.Method != "POST" {
+				http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+				return
+			}
+			bulkTag(w, r, sqlDB)
+		case rest == "suggest-topics":
+			if r.Method != "GET" {
+				http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+				return
+			}
+			verse, idx, ok := verseIndex(w, sqlDB, index, rawID)
+			if !ok {
+				return
+			}
+			neighbours, _ := strconv.Atoi(r.URL.Query().Get("neighbours"))
+			if neighbours <= 0 || neighbours > 50 {
+				neighbours = 8
+			}
+			suggestions := idx.SuggestTopics(*verse, neighbours, queryLimit(r, 5, 20))
+			writeJSON(w, map[string]any{"verse": verse, "current": verse.TopicList(), "suggestions": suggestions})
+		case rest == "":
+			id, err := strconv.Atoi(rawID)
+			if err != nil {
+				http.Error(w, `{"error":"bad_id"}`, http.StatusBadRequest)
+				return
+			}
+			switch r.Method {
+			case "GET":
+				v, err := db.GetVerseByID(sqlDB, id)
+				if err != nil {
+					if err.Error() == "not_found" {
+						http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+						return
+					}
+					log.Printf("[admin] get verse error: %v", err)
+					http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+					return
+				}
+				writeJSON(w, v)
+			case "PUT":
+				saveVerse(w, r, sqlDB, id)
+			case "DELETE":
+				if err := db.DeleteVerse(sqlDB, id, adminActor(r)); err != nil {
+					if err.Error() == "not_found" {
+						http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+						return
+					}
+					log.Printf("[admin] delete verse error: %v", err)
+					http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+					return
+				}
+				log.Printf("[admin] %s removed verse %d", adminActor(r), id)
+				writeJSON(w, map[string]any{"success": true, "id": id})
+			default:
+				http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			}
+		default:
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+		}
+	})))
+
+	mux.HandleFunc("/api/admin/topics", protect(sqlDB, auth.Reader, auth.Editor, invalidating(index, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			topics, err := db.ListTopics(sqlDB)
+			if err != nil {
+				log.Printf("[admin] list topics error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, topics)
+		case "POST":
+			// A topic exists once a verse carries it, so creating one
+			// means tagging its first verses.
+			var data struct {
+				Name     string `json:"name"`
+				VerseIDs []int  `json:"verse_ids"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			name := strings.ToLower(strings.TrimSpace(data.Name))
+			if !topicPattern.MatchString(name) {
+				http.Error(w, `{"error":"bad_topic"}`, http.StatusBadRequest)
+				return
+			}
+			if len(data.VerseIDs) == 0 {
+				http.Error(w, `{"error":"verse_ids_required"}`, http.StatusBadRequest)
+				return
+			}
+			changed, err := db.TagVerses(sqlDB, data.VerseIDs, []string{name}, nil, adminActor(r))
+			if err != nil {
+				log.Printf("[admin] create topic error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			log.Printf("[admin] %s tagged %d verse(s) with new topic %q", adminActor(r), len(changed), name)
+			writeJSON(w, map[string]any{"topic": name, "changed": nonNil(changed)})
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// PUT /api/admin/topics/{name} {"name":"new"} renames a topic everywhere;
+	// DELETE removes it from every verse, unless rules or upcoming overrides
+	// still select it.
+	mux.HandleFunc("/api/admin/topics/", protect(sqlDB, auth.Reader, auth.Editor, invalidating(index, func(w http.ResponseWriter, r *http.Request) {
+		name := strings.ToLower(r.URL.Path[len("/api/admin/topics/"):])
+		var n int
+		var err error
+		switch r.Method {
+		case "PUT":
+			var data struct {
+				Name string `json:"name"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			to := strings.ToLower(strings.TrimSpace(data.Name))
+			if !topicPattern.MatchString(to) {
+				http.Error(w, `{"error":"bad_topic"}`, http.StatusBadRequest)
+				return
+			}
+			n, err = db.RenameTopic(sqlDB, name, to, adminActor(r))
+			if err == nil {
+				log.Printf("[admin] %s renamed topic %q to %q on %d verse(s)", adminActor(r), name, to, n)
+			}
+		case "DELETE":
+			n, err = db.RemoveTopic(sqlDB, name, adminActor(r))
+			if err == nil {
+				log.Printf("[admin] %s removed topic %q from %d verse(s)", adminActor(r), name, n)
+			}
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+			return
+		}
+		var inUse *db.TopicInUse
+		if errors.As(err, &inUse) {
+			writeJSONStatus(w, http.StatusConflict, map[string]any{"error": "topic_in_use",
+				"overrides": inUse.Overrides, "rules": inUse.Rules})
+			return
+		}
+		if err != nil {
+			if err.Error() == "not_found" {
+				http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+				return
+			}
+			log.Printf("[admin] change topic error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]any{"success": true, "verses": n})
+	})))
+
+	// Verse text in other languages, used for subscribers who chose one.
+	mux.HandleFunc("/api/admin/translations", protect(sqlDB, auth.Reader, auth.Editor, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			list, err := db.ListTranslations(sqlDB, strings.ToLower(r.URL.Query().Get("language")))
+			if err != nil {
+				log.Printf("[admin] list translations error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			if list == nil {
+				list = []db.Translation{}
+			}
+			writeJSON(w, list)
+		case "POST":
+			var t db.Translation
+			if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+				http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+				return
+			}
+			t.Language = strings.ToLower(strings.TrimSpace(t.Language))
+			t.Text = strings.TrimSpace(t.Text)
+			if t.Source == "" || t.Ref == "" || t.Language == "" || t.Text == "" {
+				http.Error(w, `{"error":"missing_required_fields"}`, http.StatusBadRequest)
+				return
+			}
+			if err := db.UpsertTranslation(sqlDB, t); err != nil {
+				log.Printf("[admin] save translation error: %v", err)
+				http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+				return
+			}
+			log.Printf("[admin] %s saved %s translation of %s %s", adminActor(r), t.Language, t.Source, t.Ref)
+			writeJSON(w, t)
+		default:
+			http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		}
+	}))
+
+	// GET /api/admin/audit?entity=verse&entity_id=12&limit= lists recent
+	// content changes, newest first.
+	mux.HandleFunc("/api/admin/audit", protect(sqlDB, auth.Admin, auth.Admin, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		entries, err := db.ListAudit(sqlDB, q.Get("entity"), q.Get("entity_id"), queryLimit(r, 100, 1000))
+		if err != nil {
+			log.Printf("[admin] list audit error: %v", err)
+			http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []db.AuditEntry{}
+		}
+		writeJSON(w, entries)
+	}))
+}
+
+var topicPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// saveVerse creates a verse (id 0) or replaces verse id from the request
+// body. Verses may be saved without topics and tagged later.
+func saveVerse(w http.ResponseWriter, r *http.Request, sqlDB *sql.DB, id int) {
+	var v scripture.Verse
+	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+		http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+		return
+	}
+	v.ID = id
+	v.Source = strings.TrimSpace(v.Source)
+	v.Ref = strings.Join(strings.Fields(v.Ref), " ")
+	v.Text = strings.TrimSpace(v.Text)
+
+	var problems []string
+	sources, err := db.ListSources(sqlDB, false)
+	if err != nil {
+		log.Printf("[admin] save verse error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	known := false
+	for _, s := range sources {
+		known = known || s.ID == v.Source
+	}
+	if !known {
+		problems = append(problems, "source must be one of the registered sources")
+	}
+	if v.Ref == "" || len(v.Ref) > 100 {
+		problems = append(problems, "ref is required and at most 100 characters")
+	}
+	if v.Text == "" {
+		problems = append(problems, "text is required")
+	}
+	var topics []string
+	for _, t := range strings.Split(strings.ToLower(v.Topics), ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if !topicPattern.MatchString(t) {
+			problems = append(problems, "invalid topic "+strconv.Quote(t))
+		}
+		topics = append(topics, t)
+	}
+	if len(problems) > 0 {
+		writeJSONStatus(w, http.StatusBadRequest, map[string]any{"error": "invalid_verse", "problems": problems})
+		return
+	}
+	v.Topics = db.JoinTopics(topics)
+
+	dup, err := db.FindVerseByRef(sqlDB, v.Source, v.Ref, id)
+	switch {
+	case err == nil:
+		writeJSONStatus(w, http.StatusConflict, map[string]any{"error": "duplicate_ref", "existing": dup})
+		return
+	case err.Error() != "not_found":
+		log.Printf("[admin] save verse error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	actor := adminActor(r)
+	if id == 0 {
+		v.ID, err = db.CreateVerse(sqlDB, v, actor)
+	} else {
+		err = db.UpdateVerse(sqlDB, v, actor)
+	}
+	if err != nil {
+		if err.Error() == "not_found" {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return
+		}
+		log.Printf("[admin] save verse error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[admin] %s saved verse %d (%s %s)", actor, v.ID, v.Source, v.Ref)
+	writeJSON(w, v)
+}
+
+// bulkTag adds and removes topics on many verses at once:
+// {"verse_ids":[1,2],"add":["mercy"],"remove":["justice"]}.
+func bulkTag(w http.ResponseWriter, r *http.Request, sqlDB *sql.DB) {
+	var data struct {
+		VerseIDs []int    `json:"verse_ids"`
+		Add      []string `json:"add"`
+		Remove   []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, `{"error":"invalid_json"}`, http.StatusBadRequest)
+		return
+	}
+	if len(data.VerseIDs) == 0 || len(data.Add)+len(data.Remove) == 0 {
+		http.Error(w, `{"error":"verse_ids_and_topics_required"}`, http.StatusBadRequest)
+		return
+	}
+	for _, list := range [][]string{data.Add, data.Remove} {
+		for i, t := range list {
+			list[i] = strings.ToLower(strings.TrimSpace(t))
+			if !topicPattern.MatchString(list[i]) {
+				writeJSONStatus(w, http.StatusBadRequest, map[string]any{"error": "bad_topic", "topic": t})
+				return
+			}
+		}
+	}
+	changed, err := db.TagVerses(sqlDB, data.VerseIDs, data.Add, data.Remove, adminActor(r))
+	if err != nil {
+		log.Printf("[admin] bulk tag error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[admin] %s bulk-tagged %d of %d verse(s) (+%v -%v)", adminActor(r), len(changed), len(data.VerseIDs), data.Add, data.Remove)
+	writeJSON(w, map[string]any{"changed": nonNil(changed)})
+}
+
+func nonNil(ids []int) []int {
+	if ids == nil {
+		return []int{}
+	}
+	return ids
+}
+
+// invalidating drops the cached similarity index after every request that
+// may have changed verses or their topics.
+func invalidating(index *similarity.Cache, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		h(w, r)
+		if r.Method != "GET" && r.Method != "HEAD" {
+			index.Invalidate()
+		}
+	}
+}
+
+// verseIndex loads the verse with the given ID and the cached similarity
+// index over all verses, writing an error response and returning ok=false
+// on failure.
+func verseIndex(w http.ResponseWriter, sqlDB *sql.DB, index *similarity.Cache, rawID string) (*scripture.Verse, *similarity.Index, bool) {
+	id, err := strconv.Atoi(rawID)
+	if err != nil {
+		http.Error(w, `{"error":"bad_id"}`, http.StatusBadRequest)
+		return nil, nil, false
+	}
+	verse, err := db.GetVerseByID(sqlDB, id)
+	if err != nil {
+		if err.Error() == "not_found" {
+			http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+			return nil, nil, false
+		}
+		log.Printf("[api] load verse %d error: %v", id, err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	idx, err := index.Get()
+	if err != nil {
+		log.Printf("[api] list verses error: %v", err)
+		http.Error(w, `{"error":"server_error"}`, http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	return verse, idx, true
+}
+
+// queryLimit reads ?limit=, defaulting to def and capped at max.
+func queryLimit(r *http.Request, def, max int) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || n <= 0 {
+		return def
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
@@ -0,0 +1,100 @@
+// Command apikey mints, lists and revokes API keys for the admin and
+// sending routes.
+//
+//	apikey create -name ci-deploy -role editor
+//	apikey list
+//	apikey revoke <id or prefix>
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cfg := config.Load()
+
+	switch os.Args[1] {
+	case "create":
+		fs := flag.NewFlagSet("create", flag.ExitOnError)
+		name := fs.String("name", "", "who or what the key is for, recorded as the author of changes")
+		role := fs.String("role", "", "one of "+roleList())
+		fs.Parse(os.Args[2:])
+		if strings.TrimSpace(*name) == "" || !auth.Role(*role).Valid() {
+			fs.Usage()
+			os.Exit(2)
+		}
+		key, prefix, hash, err := auth.NewKey()
+		if err != nil {
+			fail(err)
+		}
+		sqlDB := db.Connect(cfg.DatabaseURL)
+		id, err := db.CreateAPIKey(sqlDB, strings.TrimSpace(*name), auth.Role(*role), prefix, hash)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "created key %d (%s, %s). It is shown only once:\n", id, *name, *role)
+		fmt.Println(key)
+	case "list":
+		keys, err := db.ListAPIKeys(db.Connect(cfg.DatabaseURL))
+		if err != nil {
+			fail(err)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tPREFIX\tNAME\tROLE\tCREATED\tLAST USED\tREVOKED")
+		for _, k := range keys {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Prefix, k.Name, k.Role,
+				k.CreatedAt.Format("2006-01-02"), orDash(k.LastUsedAt), orDash(k.RevokedAt))
+		}
+		tw.Flush()
+	case "revoke":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		if err := db.RevokeAPIKey(db.Connect(cfg.DatabaseURL), os.Args[2]); err != nil {
+			if err.Error() == "not_found" {
+				fail(fmt.Errorf("no active key with id or prefix %q", os.Args[2]))
+			}
+			fail(err)
+		}
+		fmt.Fprintf(os.Stderr, "revoked key %s\n", os.Args[2])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage:\n  apikey create -name NAME -role %s\n  apikey list\n  apikey revoke ID|PREFIX\n", roleList())
+	os.Exit(2)
+}
+
+func roleList() string {
+	names := make([]string, len(auth.Roles))
+	for i, r := range auth.Roles {
+		names[i] = string(r)
+	}
+	return strings.Join(names, "|")
+}
+
+func orDash(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "[apikey] %v\n", err)
+	os.Exit(1)
+}
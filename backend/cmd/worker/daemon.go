@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/email"
+	"github.com/your/module/internal/scheduler"
+	"github.com/your/module/internal/summary"
+)
+
+// leaderLockKey is the Postgres advisory lock held by the replica that
+// runs scheduled jobs. Other replicas wait on it as hot standbys.
+const leaderLockKey int64 = 0x5343524950545552 // "SCRIPTUR"
+
+// runDaemon runs the generate and deliver jobs on their cron schedules until
+// SIGINT or SIGTERM. Only the replica holding the advisory lock runs jobs.
+func runDaemon(cfg config.Config, sqlDB *sql.DB, provider summary.Provider) {
+	loc, err := time.LoadLocation(cfg.WorkerTZ)
+	if err != nil {
+		log.Fatalf("[worker] bad WORKER_TZ %q: %v", cfg.WorkerTZ, err)
+	}
+	emailCfg := email.LoadConfig()
+	ent := newEntitlement(cfg)
+	track := newTracking(cfg)
+
+	var jobs []scheduler.Job
+	if cfg.GenerateCron != "off" {
+		sched, err := scheduler.Parse(cfg.GenerateCron)
+		if err != nil {
+			log.Fatalf("[worker] WORKER_GENERATE_CRON: %v", err)
+		}
+		jobs = append(jobs, scheduler.Job{
+			Name:     "generate",
+			Schedule: sched,
+			Run: func(ctx context.Context) error {
+				// Local dates around the world run from the date at
+				// UTC-12 to the date at UTC+14. Generate through the date
+				// UTC+14 reaches a day from now, so a daily schedule never
+				// leaves the furthest-ahead zones without a payload. Never
+				// overwrite here: a restart or failover must not change a
+				// payload that may already have been delivered.
+				utc := time.Now().UTC()
+				first := utc.Add(-12 * time.Hour).Truncate(24 * time.Hour)
+				last := utc.Add(14 * time.Hour).Truncate(24 * time.Hour)
+				dates := dateRange(first, last.AddDate(0, 0, 1))
+				return generate(sqlDB, dates, generateOptions{skipExisting: true, reason: "scheduled generation", provider: provider})
+			},
+		})
+	}
+	if cfg.DeliverCron != "off" {
+		sched, err := scheduler.Parse(cfg.DeliverCron)
+		if err != nil {
+			log.Fatalf("[worker] WORKER_DELIVER_CRON: %v", err)
+		}
+		jobs = append(jobs, scheduler.Job{
+			Name:     "deliver",
+			Schedule: sched,
+			Run: func(ctx context.Context) error {
+				now := time.Now()
+				err := deliverDue(ctx, sqlDB, emailCfg, ent, track, now, cfg.DeliveryHour)
+				if derr := deliverDigests(ctx, sqlDB, emailCfg, ent, now, cfg.DeliveryHour); derr != nil && err == nil {
+					err = derr
+				}
+				return err
+			},
+		})
+	}
+	if len(jobs) == 0 {
+		log.Fatalf("[worker] daemon mode has nothing to do: both WORKER_GENERATE_CRON and WORKER_DELIVER_CRON are off")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("[worker] daemon started (%d job(s), timezone %s), waiting for leadership", len(jobs), loc)
+	scheduler.Lead(ctx, sqlDB, leaderLockKey, 15*time.Second, func(ctx context.Context) {
+		scheduler.Run(ctx, loc, jobs)
+	})
+	log.Printf("[worker] daemon stopped")
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/auth"
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/email"
+	"github.com/your/module/internal/scripture"
+)
+
+// deliverDue emails each active subscriber the payload for their local
+// date once it is at least their delivery hour (or hour, if they have not
+// chosen one) in their time zone. Each send is claimed in the deliveries
+// table first, so running this hourly (or on a second replica) never emails
+// anyone twice for the same date. With a negative hour delivery hours are
+// ignored and everyone receives their current local date's payload.
+//
+// The email honours the subscriber's preferences: it is skipped on days
+// their frequency excludes, limited to their chosen sources, and written in
+// their language with translated verse text where available. When billing
+// is required, only entitled subscribers are sent anything. With tracking
+// on, opens and clicks are recorded for subscribers who have not opted out.
+func deliverDue(ctx context.Context, sqlDB *sql.DB, emailCfg *email.Config, ent entitlement, track tracking, now time.Time, hour int) error {
+	started := time.Now()
+	subs, err := db.ListActiveSubscribers(sqlDB)
+	if err != nil {
+		return fmt.Errorf("listing subscribers: %w", err)
+	}
+	smtpReady := emailCfg.SMTPUser != "" && emailCfg.SMTPPassword != ""
+
+	payloads := map[string]*scripture.Daily{}
+	translated := translations{}
+	missing := map[string]bool{}
+	var sent, failed, bounced, notDue, notWanted, unpaid, wouldSend int
+	for _, s := range subs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !ent.allows(s, now) {
+			unpaid++
+			continue
+		}
+		local := localTime(s, now)
+		prefs := s.Preferences
+		if local.Hour() < dueHour(prefs, hour) {
+			notDue++
+			continue
+		}
+		if !prefs.Wants(local.Weekday()) {
+			notWanted++
+			continue
+		}
+		date := local.Format(dateLayout)
+
+		if missing[date] {
+			continue
+		}
+		payload, ok := payloads[date]
+		if !ok {
+			p, err := db.GetDailyPayloadDate(sqlDB, date)
+			if err != nil {
+				log.Printf("[deliver] no payload for %s: %v", date, err)
+				missing[date] = true
+				continue
+			}
+			// Never email a payload with empty passages.
+			if err := p.Validate(); err != nil {
+				log.Printf("[deliver] payload for %s is not publishable (%v), not sending", date, err)
+				missing[date] = true
+				continue
+			}
+			payload = p
+			payloads[date] = payload
+		}
+
+		payload = translated.get(sqlDB, payload, prefs.Language)
+
+		if !smtpReady {
+			wouldSend++
+			continue
+		}
+		claimed, err := db.ClaimDelivery(sqlDB, date, s.ID)
+		if err != nil {
+			return fmt.Errorf("claiming delivery to subscriber %d: %w", s.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+		status, errMsg := "sent", ""
+		tr := track.start(sqlDB, s, date, payload.Area)
+		if err := email.SendDailyScriptureEmail(emailCfg, s.Email, payload,
+			email.Options{Sources: prefs.Sources, Language: prefs.Language, Tracking: tr}); err != nil {
+			log.Printf("[deliver] ERROR sending %s to %s: %v", date, s.Email, err)
+			status, errMsg = failureStatus(err), err.Error()
+			if status == "bounced" {
+				bounced++
+			} else {
+				failed++
+			}
+		} else {
+			sent++
+		}
+		track.finish(sqlDB, tr, status)
+		if err := db.FinishDelivery(sqlDB, date, s.ID, status, errMsg); err != nil {
+			log.Printf("[deliver] recording delivery to subscriber %d: %v", s.ID, err)
+		}
+	}
+
+	if !smtpReady {
+		log.Printf("[deliver] SMTP not configured - would send to %d subscriber(s)", wouldSend)
+	}
+	log.Printf("[deliver] %d sent, %d failed, %d bounced, %d not yet due, %d not wanted today, %d not entitled",
+		sent, failed, bounced, notDue, notWanted, unpaid)
+	recordRun(sqlDB, "daily", started, sent, failed, bounced)
+	if len(missing) > 0 {
+		return fmt.Errorf("%d date(s) had no complete payload to deliver", len(missing))
+	}
+	return nil
+}
+
+// failureStatus is the delivery status of a failed send: "bounced" if the
+// mail server permanently rejected the recipient address, so retrying will
+// not help, and "failed" otherwise. Only replies to RCPT count: with an
+// enhanced status code it must be 5.1.x (a bad address), without one the
+// reply must be 550 to 553 and not a relay denial. Authentication failures
+// (530, 535) and other 5xx replies are the sender's problem.
+func failureStatus(err error) string {
+	var rcptErr *email.RecipientError
+	var smtpErr *textproto.Error
+	if !errors.As(err, &rcptErr) || !errors.As(rcptErr.Err, &smtpErr) {
+		return "failed"
+	}
+	if enhanced, _, _ := strings.Cut(smtpErr.Msg, " "); enhancedStatus.MatchString(enhanced) {
+		if strings.HasPrefix(enhanced, "5.1.") {
+			return "bounced"
+		}
+		return "failed"
+	}
+	if smtpErr.Code >= 550 && smtpErr.Code <= 553 && !strings.Contains(strings.ToLower(smtpErr.Msg), "relay") {
+		return "bounced"
+	}
+	return "failed"
+}
+
+// enhancedStatus matches an RFC 3463 enhanced status code such as 5.1.1.
+var enhancedStatus = regexp.MustCompile(`^[245]\.\d{1,3}\.\d{1,3}$`)
+
+// recordRun stores the outcome of a delivery run that sent anything, for
+// the delivery report.
+func recordRun(sqlDB *sql.DB, kind string, started time.Time, sent, failed, bounced int) {
+	if sent+failed+bounced == 0 {
+		return
+	}
+	err := db.RecordDeliveryRun(sqlDB, db.DeliveryRun{Kind: kind, StartedAt: started, FinishedAt: time.Now(),
+		Sent: sent, Failed: failed, Bounced: bounced})
+	if err != nil {
+		log.Printf("[deliver] recording %s run: %v", kind, err)
+	}
+}
+
+// entitlement decides who may receive email when billing is required.
+type entitlement struct {
+	required bool
+	grace    time.Duration
+}
+
+func newEntitlement(cfg config.Config) entitlement {
+	return entitlement{required: cfg.BillingRequired, grace: time.Duration(cfg.BillingGraceDays) * 24 * time.Hour}
+}
+
+func (e entitlement) allows(s db.Subscriber, now time.Time) bool {
+	return !e.required || s.Billing.Entitled(now, e.grace)
+}
+
+// tracking adds open and click tracking to daily emails.
+type tracking struct {
+	// baseURL is where the API serves the tracking endpoints; "" turns
+	// tracking off.
+	baseURL string
+	// secret signs the opt-out link.
+	secret string
+}
+
+func newTracking(cfg config.Config) tracking {
+	if !cfg.EmailTracking {
+		return tracking{}
+	}
+	if cfg.TrackingSecret == "" {
+		log.Printf("[deliver] EMAIL_TRACKING needs TRACKING_SECRET for the opt-out link; sending untracked")
+		return tracking{}
+	}
+	return tracking{baseURL: strings.TrimRight(cfg.BaseURL, "/"), secret: cfg.TrackingSecret}
+}
+
+// start returns the tracking of the email for date to s, or nil if it is
+// not to be tracked. The email is stored as sending once it is rendered.
+func (t tracking) start(sqlDB *sql.DB, s db.Subscriber, date, topic string) *email.Tracking {
+	if t.baseURL == "" || s.Preferences.NoTracking {
+		return nil
+	}
+	token, _, err := auth.NewToken()
+	if err != nil {
+		log.Printf("[deliver] making tracking token: %v (sending untracked)", err)
+		return nil
+	}
+	return &email.Tracking{
+		BaseURL:   t.baseURL,
+		Token:     token,
+		OptOutURL: fmt.Sprintf("%s/t/optout/%d.%s", t.baseURL, s.ID, auth.OptOutSignature(t.secret, s.ID)),
+		Save: func(tr *email.Tracking) error {
+			err := db.CreateEmailMessage(sqlDB, db.EmailMessage{Token: tr.Token, Date: date, SubscriberID: s.ID,
+				Topic: topic, Links: tr.Links})
+			if err != nil {
+				log.Printf("[deliver] recording tracking of %s for subscriber %d: %v (sending untracked)", date, s.ID, err)
+			}
+			return err
+		},
+	}
+}
+
+// finish marks a tracked email with its delivery status. An email that
+// was sent untracked after all has no row, and nothing changes.
+func (t tracking) finish(sqlDB *sql.DB, tr *email.Tracking, status string) {
+	if tr == nil {
+		return
+	}
+	if err := db.FinishEmailMessage(sqlDB, tr.Token, status); err != nil {
+		log.Printf("[deliver] marking tracked email %s: %v", status, err)
+	}
+}
+
+// localTime is now in the subscriber's time zone, or UTC if it is invalid.
+func localTime(s db.Subscriber, now time.Time) time.Time {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		log.Printf("[deliver] subscriber %d has bad timezone %q, using UTC", s.ID, s.Timezone)
+		loc = time.UTC
+	}
+	return now.In(loc)
+}
+
+// dueHour is the local hour from which a subscriber's email is due: their
+// own delivery hour if they chose one, otherwise hour. A negative hour
+// means delivery hours are ignored.
+func dueHour(prefs db.Preferences, hour int) int {
+	if prefs.DeliveryHour != nil && hour >= 0 {
+		return *prefs.DeliveryHour
+	}
+	return hour
+}
+
+// translations caches translated payloads by date and language for one
+// delivery run.
+type translations map[[2]string]*scripture.Daily
+
+func (t translations) get(sqlDB *sql.DB, payload *scripture.Daily, language string) *scripture.Daily {
+	if language == "" || language == "en" {
+		return payload
+	}
+	key := [2]string{payload.Date, language}
+	if t[key] == nil {
+		t[key] = translate(sqlDB, payload, language)
+	}
+	return t[key]
+}
+
+// translate returns a copy of payload with each passage's text replaced by
+// its translation in language, where one exists.
+func translate(sqlDB *sql.DB, payload *scripture.Daily, language string) *scripture.Daily {
+	texts, err := db.GetTranslations(sqlDB, language, payload.Passages)
+	if err != nil {
+		log.Printf("[deliver] loading %s translations for %s: %v (sending the original text)", language, payload.Date, err)
+		return payload
+	}
+	if len(texts) == 0 {
+		return payload
+	}
+	out := *payload
+	out.Passages = make([]scripture.Passage, len(payload.Passages))
+	for i, p := range payload.Passages {
+		if t, ok := texts[p.Source]; ok {
+			p.Text = t
+		}
+		out.Passages[i] = p
+	}
+	return &out
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+
+	"github.com/your/module/internal/email"
+)
+
+func TestFailureStatus(t *testing.T) {
+	rcpt := func(code int, msg string) error {
+		return fmt.Errorf("failed to send email: %w", &email.RecipientError{Err: &textproto.Error{Code: code, Msg: msg}})
+	}
+	for _, tc := range []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"unknown user", rcpt(550, "5.1.1 The email account that you tried to reach does not exist"), "bounced"},
+		{"no such domain", rcpt(553, "5.1.2 Bad destination system address"), "bounced"},
+		{"mailbox unavailable, no enhanced code", rcpt(550, "Mailbox unavailable"), "bounced"},
+		{"enhanced 5.1.x outside 550-553", rcpt(554, "5.1.0 Address rejected"), "bounced"},
+		{"relay denied", rcpt(550, "5.7.1 Relaying denied"), "failed"},
+		{"relay denied, no enhanced code", rcpt(550, "Relaying not permitted"), "failed"},
+		{"mailbox full", rcpt(552, "5.2.2 Mailbox full"), "failed"},
+		{"greylisted", rcpt(451, "4.7.1 Try again later"), "failed"},
+		{"auth required", fmt.Errorf("failed to send email: %w", &textproto.Error{Code: 530, Msg: "5.7.0 Authentication Required"}), "failed"},
+		{"bad credentials", fmt.Errorf("failed to send email: %w", &textproto.Error{Code: 535, Msg: "5.7.8 Username and Password not accepted"}), "failed"},
+		{"sender rejected", fmt.Errorf("failed to send email: %w", &textproto.Error{Code: 550, Msg: "5.1.0 Sender address rejected"}), "failed"},
+		{"connection refused", errors.New("dial tcp: connection refused"), "failed"},
+	} {
+		if got := failureStatus(tc.err); got != tc.want {
+			t.Errorf("%s: failureStatus = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
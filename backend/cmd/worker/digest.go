@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/email"
+	"github.com/your/module/internal/scripture"
+)
+
+// digestDays is how many days a weekly digest covers, ending on the
+// subscriber's local date.
+const digestDays = 7
+
+// deliverDigests emails the weekly digest to each active weekly subscriber
+// whose local weekday is their digest day, once their delivery hour has
+// come (hour and a negative hour work as in deliverDue). The digest holds
+// the publishable payloads of the seven local dates ending today. Each send
+// is claimed in digest_deliveries first, so nobody gets the same week twice.
+// Like daily email, digests go only to entitled subscribers when billing is
+// required.
+func deliverDigests(ctx context.Context, sqlDB *sql.DB, emailCfg *email.Config, ent entitlement, now time.Time, hour int) error {
+	started := time.Now()
+	subs, err := db.ListActiveSubscribers(sqlDB)
+	if err != nil {
+		return fmt.Errorf("listing subscribers: %w", err)
+	}
+	smtpReady := emailCfg.SMTPUser != "" && emailCfg.SMTPPassword != ""
+
+	weeks := map[string][]scripture.Daily{}
+	translated := translations{}
+	var sent, failed, bounced, empty, wouldSend int
+	for _, s := range subs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		prefs := s.Preferences
+		if prefs.Frequency != db.FrequencyWeekly || !ent.allows(s, now) {
+			continue
+		}
+		local := localTime(s, now)
+		if local.Weekday() != prefs.DigestWeekday || local.Hour() < dueHour(prefs, hour) {
+			continue
+		}
+		end := local.Format(dateLayout)
+
+		days, ok := weeks[end]
+		if !ok {
+			days, err = collectDigest(sqlDB, local)
+			if err != nil {
+				return fmt.Errorf("collecting digest ending %s: %w", end, err)
+			}
+			weeks[end] = days
+		}
+		if len(days) == 0 {
+			empty++
+			continue
+		}
+
+		if !smtpReady {
+			wouldSend++
+			continue
+		}
+		claimed, err := db.ClaimDigest(sqlDB, end, s.ID)
+		if err != nil {
+			return fmt.Errorf("claiming digest for subscriber %d: %w", s.ID, err)
+		}
+		if !claimed {
+			continue
+		}
+		localized := make([]scripture.Daily, len(days))
+		for i := range days {
+			localized[i] = *translated.get(sqlDB, &days[i], prefs.Language)
+		}
+		status, errMsg := "sent", ""
+		if err := email.SendWeeklyDigestEmail(emailCfg, s.Email, localized,
+			email.Options{Sources: prefs.Sources, Language: prefs.Language}); err != nil {
+			log.Printf("[digest] ERROR sending week ending %s to %s: %v", end, s.Email, err)
+			status, errMsg = failureStatus(err), err.Error()
+			if status == "bounced" {
+				bounced++
+			} else {
+				failed++
+			}
+		} else {
+			sent++
+		}
+		if err := db.FinishDigest(sqlDB, end, s.ID, status, errMsg); err != nil {
+			log.Printf("[digest] recording digest for subscriber %d: %v", s.ID, err)
+		}
+	}
+
+	if !smtpReady {
+		log.Printf("[digest] SMTP not configured - would send to %d subscriber(s)", wouldSend)
+	}
+	log.Printf("[digest] %d sent, %d failed, %d bounced, %d skipped with no payloads", sent, failed, bounced, empty)
+	recordRun(sqlDB, "digest", started, sent, failed, bounced)
+	return nil
+}
+
+// collectDigest returns the publishable payloads of the digestDays dates
+// ending on end's date, oldest first. Missing or incomplete days are left
+// out rather than holding back the whole digest.
+func collectDigest(sqlDB *sql.DB, end time.Time) ([]scripture.Daily, error) {
+	from := end.AddDate(0, 0, -(digestDays - 1)).Format(dateLayout)
+	payloads, err := db.ListDailyPayloads(sqlDB, from, end.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+	var out []scripture.Daily
+	for _, p := range payloads {
+		if err := p.Validate(); err != nil {
+			log.Printf("[digest] payload for %s is not publishable (%v), leaving it out", p.Date, err)
+			continue
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
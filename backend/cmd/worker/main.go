@@ -1,58 +1,234 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/your/module/internal/config"
 	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/email"
+	"github.com/your/module/internal/scripture"
+	"github.com/your/module/internal/summary"
 )
 
-type Daily struct {
-	Date    string                 `json:"date"`
-	Area    string                 `json:"area"`
-	Quran   map[string]string      `json:"quran"`
-	Torah   map[string]string      `json:"torah"`
-	Bible   map[string]string      `json:"bible"`
-	HD      map[string]string      `json:"human_design"`
-	Summary string                 `json:"summary"`
-	Meta    map[string]interface{} `json:"meta,omitempty"`
-}
+const dateLayout = scripture.DateLayout
 
 func main() {
+	date := flag.String("date", "", "generate the payload for a single date (YYYY-MM-DD)")
+	from := flag.String("from", "", "first date of a range to generate (YYYY-MM-DD)")
+	to := flag.String("to", "", "last date of a range to generate (YYYY-MM-DD, inclusive)")
+	days := flag.Int("days", 0, "pre-schedule the next N days starting today")
+	skipExisting := flag.Bool("skip-existing", false, "leave dates that already have a payload untouched instead of overwriting them")
+	dryRun := flag.Bool("dry-run", false, "print the generated payloads as JSON without writing them")
+	deliver := flag.Bool("deliver", false, "after generating, email each active subscriber the payload for their local date")
+	digest := flag.Bool("digest", false, "after generating, email the weekly digest to subscribers whose digest day it is locally")
+	daemon := flag.Bool("daemon", false, "run continuously, generating and delivering on the WORKER_*_CRON schedules")
+	flag.Parse()
+
+	dates, err := resolveDates(time.Now(), *date, *from, *to, *days)
+	if err == nil && *dryRun && (*deliver || *digest || *daemon) {
+		err = fmt.Errorf("--dry-run cannot be combined with --deliver, --digest or --daemon")
+	}
+	if err == nil && *daemon && (*date != "" || *from != "" || *to != "" || *days != 0) {
+		// The daemon picks its own dates on every run.
+		err = fmt.Errorf("--daemon cannot be combined with --date, --from/--to or --days")
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[worker] %v\n", err)
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	cfg := config.Load()
 	sqlDB := db.Connect(cfg.DatabaseURL)
 	ensureMigrated(sqlDB)
-	db.SeedExampleVerses(sqlDB)
-
-	today := time.Now().Format("2006-01-02")
-	topic, _ := db.GetRandomTopic(sqlDB)
-	qRef, qText, _ := db.GetVerseByTopicAndSource(sqlDB, "quran", topic)
-	tRef, tText, _ := db.GetVerseByTopicAndSource(sqlDB, "torah", topic)
-	bRef, bText, _ := db.GetVerseByTopicAndSource(sqlDB, "bible", topic)
-	hdRef, hdText, _ := db.GetVerseByTopicAndSource(sqlDB, "human_design", topic)
-
-	payload := Daily{
-		Date:    today,
-		Area:    topic,
-		Quran:   map[string]string{"ref": qRef, "text": qText},
-		Torah:   map[string]string{"ref": tRef, "text": tText},
-		Bible:   map[string]string{"ref": bRef, "text": bText},
-		HD:      map[string]string{"ref": hdRef, "text": hdText},
-		Summary: "Today's theme is '" + topic + "'. Each tradition highlights this value: Qur'an (" + qRef + "), Torah (" + tRef + "), Bible (" + bRef + "), Human Design (" + hdRef + ").",
-	}
-	b, _ := json.Marshal(payload)
-	_, _ = sqlDB.Exec(`INSERT INTO daily_payloads(date, payload_json) VALUES($1,$2)
-        ON CONFLICT(date) DO UPDATE SET payload_json=excluded.payload_json`, today, string(b))
-	log.Println("[worker] seeded today's payload and exited")
+	if !*dryRun {
+		db.SeedExampleVerses(sqlDB)
+		db.SeedCalendarRules(sqlDB)
+		db.SeedSummaryContent(sqlDB)
+		if err := db.PurgeExpiredLogins(sqlDB); err != nil {
+			log.Printf("[worker] purging expired logins: %v", err)
+		}
+	}
+
+	provider, err := newSummaryProvider(cfg, sqlDB, *dryRun)
+	if err != nil {
+		log.Fatalf("[worker] %v", err)
+	}
+
+	if *daemon {
+		runDaemon(cfg, sqlDB, provider)
+		return
+	}
+
+	opts := generateOptions{skipExisting: *skipExisting, dryRun: *dryRun, reason: "worker run", provider: provider}
+	if err := generate(sqlDB, dates, opts); err != nil {
+		log.Fatalf("[worker] %v", err)
+	}
+	if *deliver {
+		if err := deliverDue(context.Background(), sqlDB, email.LoadConfig(), newEntitlement(cfg), newTracking(cfg), time.Now(), -1); err != nil {
+			log.Fatalf("[worker] delivering: %v", err)
+		}
+	}
+	if *digest {
+		if err := deliverDigests(context.Background(), sqlDB, email.LoadConfig(), newEntitlement(cfg), time.Now(), -1); err != nil {
+			log.Fatalf("[worker] sending digests: %v", err)
+		}
+	}
+}
+
+type generateOptions struct {
+	skipExisting bool
+	dryRun       bool
+	// reason is recorded on each revision the run writes.
+	reason string
+	// provider, if set, writes summaries before the templates are tried.
+	provider summary.Provider
+}
+
+// generate builds and stores (or, for a dry run, prints) the payload for
+// each date.
+func generate(sqlDB *sql.DB, dates []string, opts generateOptions) error {
+	rules, err := db.ListCalendarRules(sqlDB)
+	if err != nil {
+		log.Printf("[worker] loading calendar rules: %v (continuing without observances)", err)
+	}
+	sources, err := db.ListSources(sqlDB, true)
+	if err != nil {
+		return fmt.Errorf("loading source registry: %w", err)
+	}
+	if len(sources) == 0 {
+		return fmt.Errorf("no enabled sources in the registry")
+	}
+	sum := summarizer{provider: opts.provider}
+	sum.templates, err = db.ListSummaryTemplates(sqlDB)
+	if err != nil {
+		log.Printf("[worker] loading summary templates: %v (continuing with the default summary)", err)
+	}
+
+	var generated, skipped, refused, failed, warned int
+	var out []scripture.Daily
+	for _, day := range dates {
+		if opts.skipExisting {
+			exists, err := db.DailyPayloadExists(sqlDB, day)
+			if err != nil {
+				return fmt.Errorf("checking %s: %w", day, err)
+			}
+			if exists {
+				log.Printf("[worker] %s already has a payload, skipping", day)
+				skipped++
+				continue
+			}
+		}
+
+		payload, warnings, err := buildPayload(sqlDB, rules, sources, sum, day)
+		if err != nil {
+			log.Printf("[worker] ERROR building %s: %v", day, err)
+			failed++
+			continue
+		}
+		if len(warnings) > 0 {
+			warned++
+		}
+		if opts.dryRun {
+			out = append(out, payload)
+			continue
+		}
+		written, err := savePayload(sqlDB, payload, opts.reason)
+		if err != nil {
+			log.Printf("[worker] ERROR saving %s: %v", day, err)
+			failed++
+			continue
+		}
+		if !written {
+			refused++
+			continue
+		}
+		generated++
+	}
+
+	if opts.dryRun {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return fmt.Errorf("encoding dry-run output: %w", err)
+		}
+		log.Printf("[worker] dry run: %d payload(s) printed (%d with warnings), %d skipped, %d failed, nothing written", len(out), warned, skipped, failed)
+	} else {
+		log.Printf("[worker] wrote %d payload(s) (%d with warnings), skipped %d, refused %d incomplete, %d failed", generated, warned, skipped, refused, failed)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d date(s) could not be generated", failed)
+	}
+	return nil
+}
+
+// resolveDates turns the command-line flags into the list of dates to
+// generate. With no flags it returns just today, matching the cron job.
+func resolveDates(now time.Time, date, from, to string, days int) ([]string, error) {
+	modes := 0
+	for _, set := range []bool{date != "", from != "" || to != "", days > 0} {
+		if set {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return nil, fmt.Errorf("use only one of --date, --from/--to or --days")
+	}
+	if days < 0 {
+		return nil, fmt.Errorf("--days must be positive")
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch {
+	case date != "":
+		d, err := time.Parse(dateLayout, date)
+		if err != nil {
+			return nil, fmt.Errorf("bad --date %q: %v", date, err)
+		}
+		return []string{d.Format(dateLayout)}, nil
+	case from != "" || to != "":
+		if from == "" || to == "" {
+			return nil, fmt.Errorf("--from and --to must be used together")
+		}
+		start, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return nil, fmt.Errorf("bad --from %q: %v", from, err)
+		}
+		end, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return nil, fmt.Errorf("bad --to %q: %v", to, err)
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("--to %s is before --from %s", to, from)
+		}
+		return dateRange(start, end), nil
+	case days > 0:
+		return dateRange(today, today.AddDate(0, 0, days-1)), nil
+	}
+	return []string{today.Format(dateLayout)}, nil
+}
+
+func dateRange(start, end time.Time) []string {
+	var out []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		out = append(out, d.Format(dateLayout))
+	}
+	return out
 }
 
 func ensureMigrated(db *sql.DB) {
 	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS daily_payloads (
         date TEXT PRIMARY KEY,
-        payload_json TEXT NOT NULL,
-        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        payload_json JSONB NOT NULL,
+        schema_version INTEGER NOT NULL DEFAULT 1,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
     );`)
 }
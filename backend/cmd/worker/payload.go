@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/calendar"
+	"github.com/your/module/internal/config"
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/scripture"
+	"github.com/your/module/internal/summary"
+)
+
+// Warning is a structured note about a payload that was generated with
+// substitutions. Warnings are logged and stored under meta.warnings.
+type Warning struct {
+	Code    string `json:"code"`
+	Source  string `json:"source,omitempty"`
+	Topic   string `json:"topic,omitempty"`
+	VerseID int    `json:"verse_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// Warning codes.
+const (
+	warnPinnedVerseMissing = "pinned_verse_missing"
+	warnTopicIncomplete    = "topic_incomplete"
+	warnFallbackVerse      = "fallback_verse"
+	warnSourceEmpty        = "source_empty"
+	warnSummaryFallback    = "summary_fallback"
+	warnProviderFallback   = "summary_provider_fallback"
+)
+
+// buildPayload assembles the payload for date, with one passage for each
+// registry source in order. An editorial override for the date wins over
+// everything else: its topic is used, and any pinned verses replace the
+// random pick for their source. Pinned verses that no longer exist are
+// reported and fall back to random selection. Without an override, a
+// matching calendar rule chooses the topic, and only then is a topic picked
+// at random.
+//
+// If the chosen topic lacks a verse for some source, a non-pinned topic is
+// swapped for one that covers every source; failing that, the gap is filled
+// with an off-topic verse from the same source. Each substitution is
+// recorded as a Warning, as is falling back from the summary provider or
+// to the default summary text. An error is returned only if the database
+// fails.
+func buildPayload(sqlDB *sql.DB, rules []calendar.Rule, sources []db.Source, sum summarizer, date string) (scripture.Daily, []Warning, error) {
+	var topic string
+	var warnings []Warning
+	pinned := map[string]*scripture.Verse{}
+	meta := map[string]interface{}{}
+
+	o, err := db.GetScheduleOverride(sqlDB, date)
+	switch {
+	case err == nil:
+		topic = o.Topic
+		meta["override"] = true
+		for _, id := range o.VerseIDs {
+			v, err := db.GetVerseByID(sqlDB, id)
+			if err != nil {
+				if err.Error() != "not_found" {
+					return scripture.Daily{}, nil, fmt.Errorf("loading pinned verse %d: %w", id, err)
+				}
+				warnings = append(warnings, Warning{Code: warnPinnedVerseMissing, VerseID: id, Topic: topic,
+					Message: fmt.Sprintf("pinned verse %d no longer exists", id)})
+				continue
+			}
+			pinned[v.Source] = v
+		}
+	case err.Error() != "not_found":
+		return scripture.Daily{}, nil, fmt.Errorf("reading schedule override: %w", err)
+	}
+	topicLocked := topic != ""
+
+	if topic == "" {
+		topic = observanceTopic(sqlDB, rules, date, meta)
+	}
+	if topic == "" {
+		topic, _ = db.GetRandomTopic(sqlDB)
+	}
+
+	passages, missing, err := pickPassages(sqlDB, sources, topic, pinned)
+	if err != nil {
+		return scripture.Daily{}, nil, err
+	}
+	if len(missing) > 0 && !topicLocked {
+		incomplete := topic
+		gaps := scripture.Daily{Passages: passages}.MissingSources()
+		alt, err := alternateTopic(sqlDB, sources, topic)
+		if err != nil {
+			return scripture.Daily{}, nil, err
+		}
+		outcome := "no other topic covers every source, filling the gaps with off-topic verses"
+		if alt != "" {
+			delete(meta, "observance")
+			topic = alt
+			if passages, missing, err = pickPassages(sqlDB, sources, topic, pinned); err != nil {
+				return scripture.Daily{}, nil, err
+			}
+			outcome = fmt.Sprintf("switched to %q", alt)
+		}
+		warnings = append(warnings, Warning{Code: warnTopicIncomplete, Topic: incomplete,
+			Message: fmt.Sprintf("topic %q has no verse for %v, %s", incomplete, gaps, outcome)})
+	}
+	for _, i := range missing {
+		source := passages[i].Source
+		ref, text, err := db.GetRandomVerseBySource(sqlDB, source)
+		switch {
+		case err == nil:
+			passages[i].Ref, passages[i].Text, passages[i].Citation = ref, text, sources[i].Cite(ref)
+			warnings = append(warnings, Warning{Code: warnFallbackVerse, Source: source, Topic: topic,
+				Message: fmt.Sprintf("no %s verse for %q, used off-topic %s", source, topic, ref)})
+		case errors.Is(err, sql.ErrNoRows):
+			warnings = append(warnings, Warning{Code: warnSourceEmpty, Source: source, Topic: topic,
+				Message: fmt.Sprintf("no %s verses exist at all", source)})
+		default:
+			return scripture.Daily{}, nil, fmt.Errorf("loading fallback %s verse: %w", source, err)
+		}
+	}
+
+	text, warning, err := sum.summarize(sqlDB, date, topic, passages, meta)
+	if err != nil {
+		return scripture.Daily{}, nil, err
+	}
+	if warning != nil {
+		warnings = append(warnings, *warning)
+	}
+
+	for _, w := range warnings {
+		log.Printf("[worker] WARNING %s: %s: %s", date, w.Code, w.Message)
+	}
+	if len(warnings) > 0 {
+		meta["warnings"] = warnings
+	}
+
+	payload := scripture.Daily{
+		SchemaVersion: scripture.SchemaVersion,
+		Date:          date,
+		Area:          topic,
+		Passages:      passages,
+		Summary:       text,
+	}
+	if len(meta) > 0 {
+		payload.Meta = meta
+	}
+	return payload, warnings, nil
+}
+
+// pickPassages selects one passage per source for topic, using pinned
+// verses where given. The indexes of passages with no matching verse are
+// returned in missing; those passages are left empty.
+func pickPassages(sqlDB *sql.DB, sources []db.Source, topic string, pinned map[string]*scripture.Verse) ([]scripture.Passage, []int, error) {
+	passages := make([]scripture.Passage, len(sources))
+	var missing []int
+	for i, s := range sources {
+		passages[i] = scripture.Passage{Source: s.ID, Name: s.Name}
+		if v := pinned[s.ID]; v != nil {
+			passages[i].Ref, passages[i].Text, passages[i].Citation = v.Ref, v.Text, s.Cite(v.Ref)
+			continue
+		}
+		ref, text, err := db.GetVerseByTopicAndSource(sqlDB, s.ID, topic)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("loading %s verse for %q: %w", s.ID, topic, err)
+		}
+		if ref == "" || text == "" {
+			missing = append(missing, i)
+			continue
+		}
+		passages[i].Ref, passages[i].Text, passages[i].Citation = ref, text, s.Cite(ref)
+	}
+	return passages, missing, nil
+}
+
+// alternateTopic returns a random topic other than current that has a verse
+// in every source, or "" if there is none.
+func alternateTopic(sqlDB *sql.DB, sources []db.Source, current string) (string, error) {
+	ids := make([]string, len(sources))
+	for i, s := range sources {
+		ids[i] = s.ID
+	}
+	topics, err := db.CompleteTopics(sqlDB, ids)
+	if err != nil {
+		return "", fmt.Errorf("listing complete topics: %w", err)
+	}
+	var candidates []string
+	for _, t := range topics {
+		if t != current {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+	return candidates[rand.Intn(len(candidates))], nil
+}
+
+// summarizer writes the "Common Ground" text from the summary templates,
+// optionally handing the passages to an external provider first.
+type summarizer struct {
+	templates []summary.Template
+	// provider, if set, is tried before the templates. Its output must
+	// already be checked (see newSummaryProvider).
+	provider summary.Provider
+}
+
+// summarize writes the summary for topic. The blurb and template are rotated
+// by how many earlier dates featured the topic, so a recurring topic reads
+// differently each time; what was used is recorded in meta. A provider or
+// template failure falls back to the next option and is returned as a
+// warning. An error is returned only if the database fails.
+func (s summarizer) summarize(sqlDB *sql.DB, date, topic string, passages []scripture.Passage, meta map[string]interface{}) (string, *Warning, error) {
+	occurrence, err := db.CountTopicOccurrences(sqlDB, topic, date)
+	if err != nil {
+		return "", nil, fmt.Errorf("counting earlier %q dates: %w", topic, err)
+	}
+	in := summary.Input{Date: date, Topic: topic, Passages: passages}
+
+	blurbs, err := db.ListTopicBlurbs(sqlDB, topic)
+	if err != nil {
+		return "", nil, fmt.Errorf("loading blurbs for %q: %w", topic, err)
+	}
+	if b, ok := summary.Rotate(blurbs, occurrence); ok {
+		in.Blurb = b.Body
+		meta["summary_blurb"] = b.ID
+	}
+
+	var warning *Warning
+	if s.provider != nil {
+		text, err := s.provider.Summarize(context.Background(), in)
+		if err == nil {
+			meta["summary_provider"] = s.provider.Name()
+			return text, nil, nil
+		}
+		warning = &Warning{Code: warnProviderFallback, Topic: topic,
+			Message: fmt.Sprintf("summary provider %s failed, used a template: %v", s.provider.Name(), err)}
+	}
+
+	t, ok := summary.Rotate(summary.Variants(s.templates, topic), occurrence)
+	if !ok {
+		text, _ := summary.Render(summary.Default, in)
+		return text, warning, nil
+	}
+	text, err := summary.Render(t.Body, in)
+	if err != nil {
+		// A provider failure is the more useful warning to keep; the
+		// template error is still logged.
+		log.Printf("[worker] %s: summary template %d failed: %v", date, t.ID, err)
+		if warning == nil {
+			warning = &Warning{Code: warnSummaryFallback, Topic: topic,
+				Message: fmt.Sprintf("summary template %d failed, used the default text: %v", t.ID, err)}
+		}
+		text, _ = summary.Render(summary.Default, in)
+		return text, warning, nil
+	}
+	meta["summary_template"] = t.ID
+	return text, warning, nil
+}
+
+// newSummaryProvider returns the provider selected by SUMMARY_PROVIDER, or
+// nil to use templates only. Its summaries pass summary.Safe with
+// SUMMARY_BLOCKLIST. A dry run reads the summary cache but never writes it.
+func newSummaryProvider(cfg config.Config, sqlDB *sql.DB, dryRun bool) (summary.Provider, error) {
+	var blocked []string
+	for _, phrase := range strings.Split(cfg.SummaryBlocklist, ",") {
+		if phrase = strings.ToLower(strings.TrimSpace(phrase)); phrase != "" {
+			blocked = append(blocked, phrase)
+		}
+	}
+	switch cfg.SummaryProvider {
+	case "", "template":
+		return nil, nil
+	case "chat":
+		p := &summary.ChatProvider{
+			BaseURL: cfg.SummaryURL,
+			Model:   cfg.SummaryModel,
+			APIKey:  cfg.SummaryAPIKey,
+			Timeout: time.Duration(cfg.SummaryTimeout) * time.Second,
+		}
+		return summary.Cached(p, db.SummaryCache{DB: sqlDB, ReadOnly: dryRun}, blocked), nil
+	}
+	return nil, fmt.Errorf("unknown SUMMARY_PROVIDER %q (want template or chat)", cfg.SummaryProvider)
+}
+
+// observanceTopic returns the topic of the calendar rule in effect on date,
+// or "" if none applies or its topic has no verses.
+func observanceTopic(sqlDB *sql.DB, rules []calendar.Rule, date string, meta map[string]interface{}) string {
+	day, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return ""
+	}
+	r := calendar.Select(rules, day)
+	if r == nil {
+		return ""
+	}
+	ok, err := db.TopicExists(sqlDB, r.Topic)
+	if err != nil || !ok {
+		log.Printf("[worker] %s: observance %q has no verses for topic %q, picking at random", date, r.Name, r.Topic)
+		return ""
+	}
+	log.Printf("[worker] %s: observance %q selects topic %q", date, r.Name, r.Topic)
+	meta["observance"] = r.Name
+	return r.Topic
+}
+
+// savePayload writes payload unless it is incomplete and would replace a
+// complete payload already stored for the date. Every write is kept as a
+// revision authored by "worker". It reports whether the payload was written.
+func savePayload(sqlDB *sql.DB, payload scripture.Daily, reason string) (bool, error) {
+	if missing := payload.MissingSources(); len(missing) > 0 {
+		existing, err := db.GetDailyPayloadDate(sqlDB, payload.Date)
+		if err != nil && err.Error() != "not_found" {
+			return false, err
+		}
+		if existing != nil && existing.Validate() == nil {
+			log.Printf("[worker] %s: refusing to replace a complete payload with one missing %v", payload.Date, missing)
+			return false, nil
+		}
+	}
+	revision, err := db.SaveDailyPayload(sqlDB, payload, "worker", reason)
+	if err != nil {
+		return false, err
+	}
+	log.Printf("[worker] %s: saved revision %d", payload.Date, revision)
+	return true, nil
+}
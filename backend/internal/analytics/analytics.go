@@ -0,0 +1,300 @@
+// Package analytics counts page views per day and endpoint. Unique
+// visitors are counted from a hash of the client's IP address and
+// User-Agent under a random salt that changes every UTC day, so visitors
+// cannot be followed from one day to the next and no address is stored.
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your/module/internal/db"
+)
+
+// botMarkers are User-Agent substrings of crawlers, scripts, link
+// previews and health checks, compared in lower case.
+var botMarkers = []string{
+	"bot", "crawler", "spider", "slurp", "curl", "wget", "python-requests",
+	"python-urllib", "go-http-client", "java/", "okhttp", "axios", "node-fetch",
+	"headless", "lighthouse", "monitor", "uptime", "pingdom", "probe",
+	"healthcheck", "preview", "facebookexternalhit", "embedly", "whatsapp",
+}
+
+// IsBot reports whether a User-Agent belongs to something other than a
+// person's browser. Requests without one are treated as bots.
+func IsBot(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+	for _, m := range botMarkers {
+		if strings.Contains(ua, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP is the address the request came from: the last entry of
+// X-Forwarded-For when behind a proxy, else the connection's address. The
+// proxy appends the address it saw to whatever the client sent, so only
+// the last entry can be trusted.
+func ClientIP(r *http.Request) string {
+	if fwd := r.Header.Values("X-Forwarded-For"); len(fwd) > 0 {
+		last := fwd[len(fwd)-1]
+		if i := strings.LastIndex(last, ","); i >= 0 {
+			last = last[i+1:]
+		}
+		if ip := strings.TrimSpace(last); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// VisitorID is the salted hash that stands for a visitor within one day.
+func VisitorID(salt, ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(salt + "\x00" + ip + "\x00" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
+
+// DefaultFlushInterval is how often a Tracker writes its counts.
+const DefaultFlushInterval = 10 * time.Second
+
+// maxPending is how many distinct visitors a Tracker holds before it
+// flushes early. If flushing cannot keep up, visitors beyond twice that are
+// not counted as unique, though their hits still are.
+const maxPending = 50000
+
+// Tracker counts views in memory and writes them to the database in
+// batches, so requests never wait on the database. Run flushes
+// periodically; call Flush once more after the server has stopped.
+//
+// Until a batch is written, visitors are kept by IP address and
+// User-Agent; they are hashed with the day's salt when the batch is.
+type Tracker struct {
+	DB            *sql.DB
+	FlushInterval time.Duration
+	// store writes the batches; nil means DB.
+	store visitStore
+
+	mu      sync.Mutex
+	pending map[visitKey]*visitCount
+	size    int
+	full    chan struct{}
+
+	flushMu   sync.Mutex
+	salts     map[string]string
+	compacted string
+}
+
+// visitStore is where a Tracker writes its batches: the database, or a
+// fake in tests.
+type visitStore interface {
+	VisitorSalt(day string) (string, error)
+	RecordVisits(day, endpoint string, hits int, visitors []string) error
+	CompactVisitors(day string) error
+}
+
+// dbStore is the visitStore backed by the visitor tables.
+type dbStore struct{ db *sql.DB }
+
+func (s dbStore) VisitorSalt(day string) (string, error) { return db.VisitorSalt(s.db, day) }
+func (s dbStore) CompactVisitors(day string) error       { return db.CompactVisitors(s.db, day) }
+func (s dbStore) RecordVisits(day, endpoint string, hits int, visitors []string) error {
+	return db.RecordVisits(s.db, day, endpoint, hits, visitors)
+}
+
+func (t *Tracker) visitStore() visitStore {
+	if t.store == nil {
+		return dbStore{t.DB}
+	}
+	return t.store
+}
+
+type visitKey struct{ day, endpoint string }
+
+type visitCount struct {
+	hits     int
+	visitors map[string]bool
+}
+
+// Record counts a view of endpoint. Only GET requests from browsers count.
+func (t *Tracker) Record(r *http.Request, endpoint string) {
+	if r.Method != "GET" || IsBot(r.UserAgent()) {
+		return
+	}
+	k := visitKey{time.Now().UTC().Format("2006-01-02"), endpoint}
+	visitor := ClientIP(r) + "\x00" + r.UserAgent()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending == nil {
+		t.pending = map[visitKey]*visitCount{}
+	}
+	c := t.pending[k]
+	if c == nil {
+		c = &visitCount{visitors: map[string]bool{}}
+		t.pending[k] = c
+	}
+	c.hits++
+	if !c.visitors[visitor] && t.size < 2*maxPending {
+		c.visitors[visitor] = true
+		t.size++
+	}
+	if t.size >= maxPending {
+		select {
+		case t.kick() <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// kick returns the channel that asks Run for an early flush. t.mu must be
+// held.
+func (t *Tracker) kick() chan struct{} {
+	if t.full == nil {
+		t.full = make(chan struct{}, 1)
+	}
+	return t.full
+}
+
+// Run flushes every FlushInterval until ctx is done.
+func (t *Tracker) Run(ctx context.Context) {
+	interval := t.FlushInterval
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+	t.mu.Lock()
+	full := t.kick()
+	t.mu.Unlock()
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		case <-full:
+		}
+		if err := t.Flush(); err != nil {
+			log.Printf("[api] flushing visitor counts: %v", err)
+		}
+	}
+}
+
+// Flush writes the counts recorded so far. A batch that fails is kept and
+// retried with the next one.
+func (t *Tracker) Flush() error {
+	t.mu.Lock()
+	batch := t.pending
+	t.pending, t.size = nil, 0
+	t.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+	keys := make([]visitKey, 0, len(batch))
+	for k := range batch {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].day != keys[j].day {
+			return keys[i].day < keys[j].day
+		}
+		return keys[i].endpoint < keys[j].endpoint
+	})
+	for n, k := range keys {
+		if err := t.write(k, batch[k]); err != nil {
+			t.restore(keys[n:], batch)
+			return err
+		}
+	}
+	// Once a day's batch is in, earlier days are over.
+	if latest := keys[len(keys)-1].day; latest > t.compacted {
+		if err := t.visitStore().CompactVisitors(latest); err != nil {
+			return err
+		}
+		t.compacted = latest
+		for day := range t.salts {
+			if day < latest {
+				delete(t.salts, day)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Tracker) write(k visitKey, c *visitCount) error {
+	salt, ok := t.salts[k.day]
+	if !ok {
+		var err error
+		if salt, err = t.visitStore().VisitorSalt(k.day); err != nil {
+			return err
+		}
+		if t.salts == nil {
+			t.salts = map[string]string{}
+		}
+		t.salts[k.day] = salt
+	}
+	// A day compacted by another replica has no salt any more; its hits
+	// still count, but its visitors can no longer be told apart.
+	var ids []string
+	if salt != "" {
+		for v := range c.visitors {
+			ip, ua, _ := strings.Cut(v, "\x00")
+			ids = append(ids, VisitorID(salt, ip, ua))
+		}
+	}
+	return t.visitStore().RecordVisits(k.day, k.endpoint, c.hits, ids)
+}
+
+// restore puts the unwritten part of a batch back into the pending counts.
+// While the database is down, hits keep adding up but, as in Record,
+// visitors beyond twice maxPending are not kept, so unique visitors are
+// undercounted; the number dropped is logged.
+func (t *Tracker) restore(keys []visitKey, batch map[visitKey]*visitCount) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.pending == nil {
+		t.pending = map[visitKey]*visitCount{}
+	}
+	dropped := 0
+	for _, k := range keys {
+		old := batch[k]
+		c := t.pending[k]
+		if c == nil {
+			c = &visitCount{visitors: map[string]bool{}}
+			t.pending[k] = c
+		}
+		c.hits += old.hits
+		for v := range old.visitors {
+			switch {
+			case c.visitors[v]:
+			case t.size >= 2*maxPending:
+				dropped++
+			default:
+				c.visitors[v] = true
+				t.size++
+			}
+		}
+	}
+	if dropped > 0 {
+		log.Printf("[api] visitor backlog is full: %d visitors will not be counted as unique", dropped)
+	}
+}
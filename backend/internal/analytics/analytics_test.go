@@ -0,0 +1,297 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIsBot(t *testing.T) {
+	for _, tc := range []struct {
+		ua   string
+		want bool
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0 Safari/537.36", false},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148", false},
+		{"", true},
+		{"   ", true},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"curl/8.4.0", true},
+		{"Go-http-client/1.1", true},
+		{"python-requests/2.31", true},
+		{"Mozilla/5.0 (X11; Linux x86_64) HeadlessChrome/124.0", true},
+		{"facebookexternalhit/1.1", true},
+		{"WhatsApp/2.23.20.0", true},
+		{"UptimeRobot/2.0", true},
+	} {
+		if got := IsBot(tc.ua); got != tc.want {
+			t.Errorf("IsBot(%q) = %v, want %v", tc.ua, got, tc.want)
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string // X-Forwarded-For headers, in order
+		want       string
+	}{
+		{"direct", "203.0.113.7:52100", nil, "203.0.113.7"},
+		{"direct ipv6", "[2001:db8::1]:52100", nil, "2001:db8::1"},
+		{"no port", "203.0.113.7", nil, "203.0.113.7"},
+		{"proxy", "10.0.0.2:8080", []string{"198.51.100.4"}, "198.51.100.4"},
+		// The client can send its own header; the proxy appends the
+		// address it saw, so only the last entry counts.
+		{"spoofed entry", "10.0.0.2:8080", []string{"1.2.3.4, 198.51.100.4"}, "198.51.100.4"},
+		{"spaces", "10.0.0.2:8080", []string{"1.2.3.4 ,  198.51.100.4 "}, "198.51.100.4"},
+		{"several headers", "10.0.0.2:8080", []string{"1.2.3.4", "5.6.7.8, 198.51.100.4"}, "198.51.100.4"},
+		{"empty last entry", "10.0.0.2:8080", []string{"1.2.3.4, "}, "10.0.0.2"},
+		{"empty header", "10.0.0.2:8080", []string{""}, "10.0.0.2"},
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for _, v := range tc.forwarded {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := ClientIP(r); got != tc.want {
+			t.Errorf("%s: ClientIP = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestVisitorID(t *testing.T) {
+	id := VisitorID("salt", "203.0.113.7", "Mozilla/5.0")
+	if len(id) != 32 {
+		t.Errorf("VisitorID length = %d, want 32 hex digits", len(id))
+	}
+	if again := VisitorID("salt", "203.0.113.7", "Mozilla/5.0"); again != id {
+		t.Errorf("VisitorID not stable: %s, then %s", id, again)
+	}
+	for _, tc := range []struct{ salt, ip, ua string }{
+		{"other salt", "203.0.113.7", "Mozilla/5.0"},
+		{"salt", "203.0.113.8", "Mozilla/5.0"},
+		{"salt", "203.0.113.7", "Mozilla/5.1"},
+		// Fields are separated, so shifting text between them changes the ID.
+		{"salt", "203.0.113.7Mozilla/5.0", ""},
+		{"salt203.0.113.7", "", "Mozilla/5.0"},
+	} {
+		if got := VisitorID(tc.salt, tc.ip, tc.ua); got == id {
+			t.Errorf("VisitorID(%q, %q, %q) = VisitorID(salt, 203.0.113.7, Mozilla/5.0)", tc.salt, tc.ip, tc.ua)
+		}
+	}
+}
+
+const browser = "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0"
+
+// fakeStore records what a Tracker writes. Writes for an endpoint in
+// failing return an error until the endpoint is removed.
+type fakeStore struct {
+	mu        sync.Mutex
+	failing   map[string]bool
+	visits    map[string]fakeVisits // by endpoint
+	compacted []string
+	written   chan struct{}
+}
+
+type fakeVisits struct {
+	hits     int
+	visitors map[string]bool
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{failing: map[string]bool{}, visits: map[string]fakeVisits{}, written: make(chan struct{}, 100)}
+}
+
+func (s *fakeStore) VisitorSalt(day string) (string, error) { return "salt-" + day, nil }
+
+func (s *fakeStore) CompactVisitors(day string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compacted = append(s.compacted, day)
+	return nil
+}
+
+func (s *fakeStore) RecordVisits(day, endpoint string, hits int, visitors []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failing[endpoint] {
+		return errors.New("database is down")
+	}
+	v := s.visits[endpoint]
+	if v.visitors == nil {
+		v.visitors = map[string]bool{}
+	}
+	v.hits += hits
+	for _, id := range visitors {
+		v.visitors[id] = true
+	}
+	s.visits[endpoint] = v
+	select {
+	case s.written <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (s *fakeStore) get(endpoint string) (hits, visitors int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.visits[endpoint]
+	return v.hits, len(v.visitors)
+}
+
+// view records a GET of endpoint from ip with userAgent.
+func view(tr *Tracker, endpoint, ip, userAgent string) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = ip + ":40000"
+	r.Header.Set("User-Agent", userAgent)
+	tr.Record(r, endpoint)
+}
+
+func TestTrackerFlush(t *testing.T) {
+	store := newFakeStore()
+	tr := &Tracker{store: store}
+	view(tr, "daily", "203.0.113.1", browser)
+	view(tr, "daily", "203.0.113.1", browser)
+	view(tr, "daily", "203.0.113.2", browser)
+	view(tr, "feed", "203.0.113.1", browser)
+	view(tr, "daily", "203.0.113.3", "curl/8.4.0") // bot
+	r := httptest.NewRequest("POST", "/", nil)
+	r.Header.Set("User-Agent", browser)
+	tr.Record(r, "daily") // not a GET
+
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		endpoint       string
+		hits, visitors int
+	}{
+		{"daily", 3, 2},
+		{"feed", 1, 1},
+	} {
+		if hits, visitors := store.get(tc.endpoint); hits != tc.hits || visitors != tc.visitors {
+			t.Errorf("%s: %d hits, %d visitors, want %d, %d", tc.endpoint, hits, visitors, tc.hits, tc.visitors)
+		}
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	if len(store.compacted) != 1 || store.compacted[0] != today {
+		t.Errorf("compacted %v, want [%s]", store.compacted, today)
+	}
+
+	// Each visitor is written as its salted hash.
+	want := VisitorID("salt-"+today, "203.0.113.1", browser)
+	if !store.visits["feed"].visitors[want] {
+		t.Errorf("feed visitors %v, want %s", store.visits["feed"].visitors, want)
+	}
+
+	// Nothing is written twice, and the day is only compacted once.
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	view(tr, "daily", "203.0.113.4", browser)
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if hits, visitors := store.get("daily"); hits != 4 || visitors != 3 {
+		t.Errorf("daily after second batch: %d hits, %d visitors, want 4, 3", hits, visitors)
+	}
+	if len(store.compacted) != 1 {
+		t.Errorf("compacted %v, want one day", store.compacted)
+	}
+}
+
+func TestTrackerRetriesFailedBatch(t *testing.T) {
+	store := newFakeStore()
+	tr := &Tracker{store: store}
+	view(tr, "a", "203.0.113.1", browser)
+	view(tr, "b", "203.0.113.1", browser)
+	view(tr, "c", "203.0.113.1", browser)
+	store.failing["b"] = true
+	if err := tr.Flush(); err == nil {
+		t.Fatal("Flush succeeded with the store failing")
+	}
+	if hits, _ := store.get("a"); hits != 1 {
+		t.Errorf("a: %d hits, want 1 written before the failure", hits)
+	}
+
+	// The unwritten keys are kept and merged with new views.
+	view(tr, "b", "203.0.113.1", browser)
+	view(tr, "b", "203.0.113.2", browser)
+	delete(store.failing, "b")
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		endpoint       string
+		hits, visitors int
+	}{
+		{"a", 1, 1},
+		{"b", 3, 2},
+		{"c", 1, 1},
+	} {
+		if hits, visitors := store.get(tc.endpoint); hits != tc.hits || visitors != tc.visitors {
+			t.Errorf("%s: %d hits, %d visitors, want %d, %d", tc.endpoint, hits, visitors, tc.hits, tc.visitors)
+		}
+	}
+}
+
+func TestTrackerRestoreKeepsEveryKey(t *testing.T) {
+	store := newFakeStore()
+	tr := &Tracker{store: store}
+	// More than maxPending visitors on the first key, a few on the last.
+	for i := 0; i < maxPending+10; i++ {
+		view(tr, "a", fmt.Sprintf("10.%d.%d.%d", i>>16&255, i>>8&255, i&255), browser)
+	}
+	for i := 0; i < 5; i++ {
+		view(tr, "z", fmt.Sprintf("192.0.2.%d", i), browser)
+	}
+	store.failing["a"] = true
+	if err := tr.Flush(); err == nil {
+		t.Fatal("Flush succeeded with the store failing")
+	}
+	delete(store.failing, "a")
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if _, visitors := store.get("a"); visitors != maxPending+10 {
+		t.Errorf("a: %d visitors, want %d", visitors, maxPending+10)
+	}
+	if _, visitors := store.get("z"); visitors != 5 {
+		t.Errorf("z: %d visitors, want 5", visitors)
+	}
+}
+
+func TestTrackerFlushesEarlyWhenFull(t *testing.T) {
+	store := newFakeStore()
+	tr := &Tracker{store: store, FlushInterval: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		tr.Run(ctx)
+		close(done)
+	}()
+	defer func() { cancel(); <-done }()
+
+	for i := 0; i < maxPending; i++ {
+		view(tr, "daily", fmt.Sprintf("10.%d.%d.%d", i>>16&255, i>>8&255, i&255), browser)
+	}
+	select {
+	case <-store.written:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no early flush after maxPending visitors")
+	}
+	// Views recorded while the early flush ran are picked up by Flush.
+	if err := tr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if hits, visitors := store.get("daily"); hits != maxPending || visitors != maxPending {
+		t.Errorf("daily: %d hits, %d visitors, want %d each", hits, visitors, maxPending)
+	}
+}
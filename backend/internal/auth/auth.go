@@ -0,0 +1,122 @@
+// Package auth mints and checks API keys and the roles they carry, the
+// random tokens used for subscriber login, and the signatures of links in
+// emails that act without a session.
+//
+// A key looks like "sd_<prefix>_<secret>". Only the prefix, which is used
+// to find the key, and a SHA-256 hash of the whole key are stored. Keys are
+// 192 bits of randomness, so a fast hash is sufficient.
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Role is what a key may do.
+type Role string
+
+const (
+	// Reader can read admin data: overrides, rules, revisions, verses.
+	Reader Role = "reader"
+	// Editor can also change content.
+	Editor Role = "editor"
+	// Sender can trigger outgoing email via /api/send-daily.
+	Sender Role = "sender"
+	// Admin can do everything, including changing the source registry and
+	// reading the audit log.
+	Admin Role = "admin"
+)
+
+// Roles lists every role, for validation and help text.
+var Roles = []Role{Reader, Editor, Sender, Admin}
+
+// Valid reports whether r is a known role.
+func (r Role) Valid() bool {
+	for _, known := range Roles {
+		if r == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether a key with role r may act where required is
+// needed. Admin allows everything and editor includes reader; sender is
+// separate from content access.
+func (r Role) Allows(required Role) bool {
+	switch {
+	case r == Admin || r == required:
+		return true
+	case r == Editor && required == Reader:
+		return true
+	}
+	return false
+}
+
+const keyPrefix = "sd_"
+
+// NewKey returns a new random key, its lookup prefix and its hash.
+func NewKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 30)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+	prefix = hex.EncodeToString(b[:6])
+	key = keyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(b[6:])
+	return key, prefix, Hash(key), nil
+}
+
+// Prefix extracts the lookup prefix from a presented key.
+func Prefix(key string) (string, error) {
+	rest, ok := strings.CutPrefix(key, keyPrefix)
+	if !ok {
+		return "", fmt.Errorf("not an API key")
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 12 || secret == "" {
+		return "", fmt.Errorf("malformed API key")
+	}
+	return prefix, nil
+}
+
+// Hash is the stored form of a key.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches compares a presented key with a stored hash in constant time.
+func Matches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
+
+// NewToken returns a random single-use token, such as a magic-link or
+// session token, and its stored hash.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, Hash(token), nil
+}
+
+// OptOutSignature signs a subscriber ID for the one-click tracking opt-out
+// link in emails, which works without signing in.
+func OptOutSignature(secret string, subscriberID int) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "no_tracking:%d", subscriberID)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ValidOptOutSignature reports whether sig is OptOutSignature(secret,
+// subscriberID). It is always false without a secret.
+func ValidOptOutSignature(secret string, subscriberID int, sig string) bool {
+	return secret != "" && hmac.Equal([]byte(sig), []byte(OptOutSignature(secret, subscriberID)))
+}
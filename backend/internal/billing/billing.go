@@ -0,0 +1,92 @@
+// Package billing connects subscriptions to a payment provider. The API
+// talks to the PaymentProvider interface, so Stripe can be swapped for the
+// local Fake when developing without network access.
+package billing
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// PaymentProvider takes payments for subscriptions and reports what
+// happened to them through webhooks.
+type PaymentProvider interface {
+	// Name identifies the provider; its webhook is served at
+	// /api/webhooks/{name}.
+	Name() string
+	// CreateCheckout starts a checkout and returns where to send the payer.
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error)
+	// Cancel ends a subscription. The provider confirms with a
+	// SubscriptionEnded event.
+	Cancel(ctx context.Context, subscriptionID string) error
+	// ParseWebhook authenticates a webhook request and converts it to an
+	// Event. An *UpstreamError means the provider could not be reached
+	// to complete the event and the delivery should be retried; any other
+	// error means the request should be rejected with a 400.
+	ParseWebhook(payload []byte, header http.Header) (*Event, error)
+}
+
+// UpstreamError is returned by ParseWebhook when an authentic event could
+// not be completed because a call back to the provider failed.
+type UpstreamError struct {
+	Err error
+}
+
+func (e *UpstreamError) Error() string { return e.Err.Error() }
+func (e *UpstreamError) Unwrap() error { return e.Err }
+
+// CheckoutRequest describes the subscriber about to pay.
+type CheckoutRequest struct {
+	SubscriberID int
+	Email        string
+	// SuccessURL may contain {CHECKOUT_SESSION_ID}, which is replaced with
+	// the checkout's ID.
+	SuccessURL string
+	CancelURL  string
+}
+
+// Checkout is a started checkout.
+type Checkout struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// Event types, independent of the provider.
+const (
+	// CheckoutCompleted: the payer finished checkout and a subscription
+	// exists. It may not be paid for yet; see Event.Status.
+	CheckoutCompleted = "checkout.completed"
+	// PaymentSucceeded: a period was paid for, through PeriodEnd.
+	PaymentSucceeded = "payment.succeeded"
+	// PaymentFailed: a renewal could not be charged.
+	PaymentFailed = "payment.failed"
+	// SubscriptionChanged: the subscription is now in Status.
+	SubscriptionChanged = "subscription.changed"
+	// SubscriptionEnded: the subscription was canceled.
+	SubscriptionEnded = "subscription.ended"
+)
+
+// Event is a provider webhook event in provider-neutral form. Type is ""
+// for events the application does not act on.
+type Event struct {
+	// ID is the provider's event ID, used to ignore redeliveries.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// SubscriberID is the subscriber the checkout was created for, or 0
+	// if the provider did not report one (e.g. a bare Payment Link).
+	SubscriberID   int    `json:"subscriber_id,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Name           string `json:"name,omitempty"`
+	CustomerID     string `json:"customer_id,omitempty"`
+	SubscriptionID string `json:"subscription_id,omitempty"`
+	// Status is a db.Billing* status, set for SubscriptionChanged, and for
+	// CheckoutCompleted once the first period is paid for or a trial has
+	// started. PeriodEnd is then always set.
+	Status    string    `json:"status,omitempty"`
+	PeriodEnd time.Time `json:"period_end,omitempty"`
+	// Created is when the provider created the event. Providers do not
+	// deliver events in order, so older events than the last one applied
+	// to a subscriber are ignored.
+	Created time.Time `json:"created"`
+}
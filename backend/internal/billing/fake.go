@@ -0,0 +1,251 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/your/module/internal/db"
+)
+
+// FakePeriod is the length of a fake subscription period.
+const FakePeriod = 30 * 24 * time.Hour
+
+// Fake is a payment provider that runs entirely inside the API process.
+// Checkout is a local page with Pay and Decline buttons, and every outcome
+// is delivered to WebhookURL as a signed event, exactly as a real provider
+// would, so the whole subscribe, pay and welcome flow can be exercised
+// without network access. Its state is lost on restart.
+type Fake struct {
+	// PageURL is where the fake checkout page is served, normally
+	// BASE_URL + "/api/billing/fake/checkout".
+	PageURL string
+	// WebhookURL receives the events, normally BASE_URL +
+	// "/api/webhooks/fake".
+	WebhookURL string
+	Client     *http.Client
+
+	secret   []byte
+	mu       sync.Mutex
+	seq      int
+	sessions map[string]CheckoutRequest
+	subs     map[string]fakeSub
+}
+
+type fakeSub struct {
+	customer  string
+	periodEnd time.Time
+}
+
+// NewFake returns a Fake that serves its checkout page under baseURL. Its
+// webhook secret is random, since only this process signs and checks it.
+func NewFake(baseURL string) *Fake {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	base := strings.TrimRight(baseURL, "/")
+	return &Fake{
+		PageURL:    base + "/api/billing/fake/checkout",
+		WebhookURL: base + "/api/webhooks/fake",
+		secret:     secret,
+		sessions:   map[string]CheckoutRequest{},
+		subs:       map[string]fakeSub{},
+	}
+}
+
+func (f *Fake) Name() string { return "fake" }
+
+func (f *Fake) nextID(prefix string) string {
+	f.seq++
+	return fmt.Sprintf("%s_fake_%d_%d", prefix, time.Now().Unix(), f.seq)
+}
+
+func (f *Fake) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.nextID("cs")
+	f.sessions[id] = req
+	return &Checkout{ID: id, URL: f.PageURL + "?session=" + id}, nil
+}
+
+func (f *Fake) Cancel(ctx context.Context, subscriptionID string) error {
+	f.mu.Lock()
+	sub, ok := f.subs[subscriptionID]
+	if ok {
+		delete(f.subs, subscriptionID)
+	}
+	ev := Event{ID: f.nextID("evt"), Type: SubscriptionEnded, Status: db.BillingCanceled,
+		CustomerID: sub.customer, SubscriptionID: subscriptionID, PeriodEnd: sub.periodEnd}
+	f.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no fake subscription %q", subscriptionID)
+	}
+	return f.emit(ctx, ev)
+}
+
+func (f *Fake) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	sig, err := hex.DecodeString(header.Get("Fake-Signature"))
+	if err != nil || !hmac.Equal(sig, f.sign(payload)) {
+		return nil, fmt.Errorf("bad Fake-Signature")
+	}
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+func (f *Fake) sign(payload []byte) []byte {
+	h := hmac.New(sha256.New, f.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// emit posts a signed event to the webhook and waits for it to be handled.
+func (f *Fake) emit(ctx context.Context, ev Event) error {
+	ev.Created = time.Now().UTC()
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", f.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Fake-Signature", hex.EncodeToString(f.sign(body)))
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, raw)
+	}
+	return nil
+}
+
+// Handler serves the fake checkout and renewal routes:
+//
+//	GET  /api/billing/fake/checkout?session=       the checkout page
+//	GET  /api/billing/fake/checkout?session=&outcome=paid|declined
+//	POST /api/billing/fake/renew?subscription=&outcome=paid|failed
+//
+// A paid checkout emits CheckoutCompleted and PaymentSucceeded and
+// redirects to the success URL; a declined one emits nothing and redirects
+// to the cancel URL, as a real checkout would. Renewals emit
+// PaymentSucceeded for another period, or PaymentFailed.
+func (f *Fake) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/billing/fake/checkout", f.serveCheckout)
+	mux.HandleFunc("/api/billing/fake/renew", f.serveRenew)
+	return mux
+}
+
+func (f *Fake) serveCheckout(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("session")
+	f.mu.Lock()
+	req, ok := f.sessions[id]
+	f.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown or finished checkout session", http.StatusNotFound)
+		return
+	}
+
+	switch r.URL.Query().Get("outcome") {
+	case "":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		link := f.PageURL + "?session=" + id + "&outcome="
+		fmt.Fprintf(w, `<!doctype html><title>Fake checkout</title>
+<h1>Fake checkout</h1>
+<p>Subscriber %d (%s). No payment is taken.</p>
+<p><a href="%s">Pay</a> &middot; <a href="%s">Decline</a></p>
+`, req.SubscriberID, html.EscapeString(req.Email), html.EscapeString(link+"paid"), html.EscapeString(link+"declined"))
+		return
+	case "declined":
+		f.finish(id)
+		http.Redirect(w, r, req.CancelURL, http.StatusSeeOther)
+		return
+	case "paid":
+	default:
+		http.Error(w, "outcome must be paid or declined", http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	customer, subID := f.nextID("cus"), f.nextID("sub")
+	end := time.Now().Add(FakePeriod).UTC()
+	f.subs[subID] = fakeSub{customer: customer, periodEnd: end}
+	events := []Event{
+		{ID: f.nextID("evt"), Type: CheckoutCompleted, SubscriberID: req.SubscriberID, Email: req.Email,
+			CustomerID: customer, SubscriptionID: subID, Status: db.BillingActive, PeriodEnd: end},
+		{ID: f.nextID("evt"), Type: PaymentSucceeded, CustomerID: customer, SubscriptionID: subID, PeriodEnd: end},
+	}
+	f.mu.Unlock()
+	for _, ev := range events {
+		if err := f.emit(r.Context(), ev); err != nil {
+			http.Error(w, "delivering "+ev.Type+": "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	f.finish(id)
+	http.Redirect(w, r, strings.ReplaceAll(req.SuccessURL, "{CHECKOUT_SESSION_ID}", id), http.StatusSeeOther)
+}
+
+func (f *Fake) finish(session string) {
+	f.mu.Lock()
+	delete(f.sessions, session)
+	f.mu.Unlock()
+}
+
+func (f *Fake) serveRenew(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, `{"error":"method_not_allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+	subID := r.URL.Query().Get("subscription")
+	f.mu.Lock()
+	sub, ok := f.subs[subID]
+	ev := Event{ID: f.nextID("evt"), CustomerID: sub.customer, SubscriptionID: subID}
+	if ok {
+		switch r.URL.Query().Get("outcome") {
+		case "paid":
+			sub.periodEnd = sub.periodEnd.Add(FakePeriod)
+			f.subs[subID] = sub
+			ev.Type, ev.PeriodEnd = PaymentSucceeded, sub.periodEnd
+		case "failed":
+			ev.Type = PaymentFailed
+		}
+	}
+	f.mu.Unlock()
+	switch {
+	case !ok:
+		http.Error(w, `{"error":"not_found"}`, http.StatusNotFound)
+	case ev.Type == "":
+		http.Error(w, `{"error":"bad_outcome"}`, http.StatusBadRequest)
+	default:
+		if err := f.emit(r.Context(), ev); err != nil {
+			http.Error(w, `{"error":"webhook_failed"}`, http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ev)
+	}
+}
@@ -0,0 +1,208 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/db"
+	"github.com/your/module/internal/stripe"
+)
+
+const stripeAPI = "https://api.stripe.com/v1"
+
+// Stripe takes payments with Stripe. With a SecretKey and PriceID it creates
+// Checkout Sessions through the API; otherwise checkout falls back to the
+// PaymentLink, and Cancel is unavailable.
+type Stripe struct {
+	SecretKey     string
+	PriceID       string
+	PaymentLink   string
+	WebhookSecret string
+	// Tolerance is the allowed age of a signed webhook event.
+	Tolerance time.Duration
+	Client    *http.Client
+}
+
+func (s *Stripe) Name() string { return "stripe" }
+
+func (s *Stripe) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	ref := strconv.Itoa(req.SubscriberID)
+	if s.SecretKey == "" || s.PriceID == "" {
+		if s.PaymentLink == "" {
+			return nil, fmt.Errorf("stripe checkout needs STRIPE_SECRET_KEY and STRIPE_PRICE_ID, or STRIPE_PAYMENT_LINK")
+		}
+		// Payment Links pass client_reference_id through to the
+		// checkout.session.completed event.
+		q := url.Values{"client_reference_id": {ref}, "prefilled_email": {req.Email}}
+		return &Checkout{URL: s.PaymentLink + "?" + q.Encode()}, nil
+	}
+
+	form := url.Values{
+		"mode":                    {"subscription"},
+		"line_items[0][price]":    {s.PriceID},
+		"line_items[0][quantity]": {"1"},
+		"success_url":             {req.SuccessURL},
+		"cancel_url":              {req.CancelURL},
+		"client_reference_id":     {ref},
+		"customer_email":          {req.Email},
+	}
+	var out struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := s.call(ctx, "POST", "/checkout/sessions", form, &out); err != nil {
+		return nil, err
+	}
+	return &Checkout{ID: out.ID, URL: out.URL}, nil
+}
+
+func (s *Stripe) Cancel(ctx context.Context, subscriptionID string) error {
+	if s.SecretKey == "" {
+		return fmt.Errorf("canceling needs STRIPE_SECRET_KEY")
+	}
+	return s.call(ctx, "DELETE", "/subscriptions/"+url.PathEscape(subscriptionID), nil, nil)
+}
+
+// call sends a form-encoded request to the Stripe API and decodes the
+// response into out, if it is not nil.
+func (s *Stripe) call(ctx context.Context, method, path string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, stripeAPI+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.SecretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		_ = json.Unmarshal(raw, &e)
+		return fmt.Errorf("stripe %s %s returned %s: %s", method, path, resp.Status, e.Error.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func (s *Stripe) ParseWebhook(payload []byte, header http.Header) (*Event, error) {
+	if s.WebhookSecret == "" {
+		return nil, fmt.Errorf("STRIPE_WEBHOOK_SECRET is not set")
+	}
+	tolerance := s.Tolerance
+	if tolerance == 0 {
+		tolerance = stripe.DefaultTolerance
+	}
+	if err := stripe.Verify(payload, header.Get("Stripe-Signature"), s.WebhookSecret, tolerance, time.Now()); err != nil {
+		return nil, err
+	}
+	ev, err := stripe.Parse(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &Event{ID: ev.ID, Created: time.Unix(ev.Created, 0).UTC()}
+	switch ev.Type {
+	case stripe.CheckoutSessionCompleted:
+		var cs stripe.CheckoutSession
+		if err := json.Unmarshal(ev.Data.Object, &cs); err != nil {
+			return nil, fmt.Errorf("decoding checkout session: %w", err)
+		}
+		out.Type = CheckoutCompleted
+		out.SubscriberID, _ = strconv.Atoi(cs.ClientReferenceID)
+		out.Email, out.Name = cs.Email(), cs.CustomerDetails.Name
+		out.CustomerID, out.SubscriptionID = cs.Customer, cs.Subscription
+		if err := s.checkoutStatus(out, cs.PaymentStatus); err != nil {
+			return nil, err
+		}
+	case stripe.InvoicePaid, stripe.InvoicePaymentFailed:
+		var inv stripe.Invoice
+		if err := json.Unmarshal(ev.Data.Object, &inv); err != nil {
+			return nil, fmt.Errorf("decoding invoice: %w", err)
+		}
+		out.Type = PaymentSucceeded
+		out.PeriodEnd = inv.PeriodEnd()
+		if ev.Type == stripe.InvoicePaymentFailed {
+			// The failed invoice's period was never paid for.
+			out.Type, out.PeriodEnd = PaymentFailed, time.Time{}
+		}
+		out.CustomerID, out.SubscriptionID = inv.Customer, inv.Subscription
+	case stripe.SubscriptionCreated, stripe.SubscriptionUpdated, stripe.SubscriptionDeleted:
+		var sub stripe.Subscription
+		if err := json.Unmarshal(ev.Data.Object, &sub); err != nil {
+			return nil, fmt.Errorf("decoding subscription: %w", err)
+		}
+		out.Type, out.Status = SubscriptionChanged, subscriptionStatus(sub.Status)
+		if ev.Type == stripe.SubscriptionDeleted {
+			out.Type, out.Status = SubscriptionEnded, db.BillingCanceled
+		}
+		if out.Status == "" {
+			out.Type = ""
+		}
+		out.CustomerID, out.SubscriptionID, out.PeriodEnd = sub.Customer, sub.ID, sub.PeriodEnd()
+	}
+	return out, nil
+}
+
+// checkoutStatus sets the status and period end of a completed checkout
+// from its subscription: active if the checkout was paid, trialing until
+// the trial end if no payment was due. The webhook's session does not
+// carry the period, so it is fetched, which needs the SecretKey; without
+// one, or while payment is pending, the checkout only links the customer
+// and the invoice and subscription events set the status.
+func (s *Stripe) checkoutStatus(ev *Event, paymentStatus string) error {
+	if s.SecretKey == "" || ev.SubscriptionID == "" || (paymentStatus != "paid" && paymentStatus != "no_payment_required") {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var sub stripe.Subscription
+	if err := s.call(ctx, "GET", "/subscriptions/"+url.PathEscape(ev.SubscriptionID), nil, &sub); err != nil {
+		return &UpstreamError{Err: fmt.Errorf("fetching subscription: %w", err)}
+	}
+	switch {
+	case sub.Status == "active" && paymentStatus == "paid" && !sub.PeriodEnd().IsZero():
+		ev.Status, ev.PeriodEnd = db.BillingActive, sub.PeriodEnd()
+	case sub.Status == "trialing" && sub.TrialEnd != 0:
+		ev.Status, ev.PeriodEnd = db.BillingTrialing, time.Unix(sub.TrialEnd, 0).UTC()
+	}
+	return nil
+}
+
+// subscriptionStatus maps a Stripe subscription status to a billing status,
+// or "" for states that do not change it (incomplete, paused).
+func subscriptionStatus(status string) string {
+	switch status {
+	case "trialing":
+		return db.BillingTrialing
+	case "active":
+		return db.BillingActive
+	case "past_due", "unpaid":
+		return db.BillingPastDue
+	case "canceled", "incomplete_expired":
+		return db.BillingCanceled
+	}
+	return ""
+}
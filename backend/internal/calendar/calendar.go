@@ -0,0 +1,196 @@
+// Package calendar converts between the Gregorian, tabular Hijri and Hebrew
+// calendars and computes movable Christian feasts, so observance rules can be
+// resolved to Gregorian dates without calling any external service.
+//
+// Conversions work on fixed day numbers ("R.D." dates, where day 1 is
+// Monday, January 1 of year 1 in the proleptic Gregorian calendar), following
+// the arithmetic in Reingold & Dershowitz, Calendrical Calculations.
+package calendar
+
+import "time"
+
+// rdUnixEpoch is the fixed day number of 1970-01-01.
+const rdUnixEpoch = 719163
+
+// Hebrew month numbers. Nisan is month 1 and the civil year starts in Tishri.
+const (
+	Nisan      = 1
+	Iyyar      = 2
+	Sivan      = 3
+	Tammuz     = 4
+	Av         = 5
+	Elul       = 6
+	Tishri     = 7
+	Marheshvan = 8
+	Kislev     = 9
+	Tevet      = 10
+	Shevat     = 11
+	Adar       = 12
+	AdarII     = 13
+)
+
+// FixedFromTime returns the fixed day number of t's calendar date.
+func FixedFromTime(t time.Time) int {
+	return fixedFromGregorian(t.Year(), int(t.Month()), t.Day())
+}
+
+// TimeFromFixed returns midnight UTC on the given fixed day.
+func TimeFromFixed(rd int) time.Time {
+	return time.Unix(int64(rd-rdUnixEpoch)*86400, 0).UTC()
+}
+
+func gregorianLeapYear(y int) bool {
+	return y%4 == 0 && (y%100 != 0 || y%400 == 0)
+}
+
+func fixedFromGregorian(y, m, d int) int {
+	n := 365*(y-1) + floorDiv(y-1, 4) - floorDiv(y-1, 100) + floorDiv(y-1, 400) + floorDiv(367*m-362, 12) + d
+	switch {
+	case m <= 2:
+	case gregorianLeapYear(y):
+		n--
+	default:
+		n -= 2
+	}
+	return n
+}
+
+// Easter returns the date of (Western) Easter Sunday in the given year,
+// using the anonymous Gregorian computus.
+func Easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// islamicEpoch is the fixed day of 1 Muharram AH 1 (July 16, 622 Julian).
+const islamicEpoch = 227015
+
+// FixedFromHijri converts a date in the tabular (arithmetic) Islamic
+// calendar. Observed dates based on moon sighting can differ by a day or two.
+func FixedFromHijri(y, m, d int) int {
+	return d + 29*(m-1) + floorDiv(6*m-1, 11) + (y-1)*354 + floorDiv(3+11*y, 30) + islamicEpoch - 1
+}
+
+// HijriFromFixed converts a fixed day to the tabular Islamic calendar.
+func HijriFromFixed(rd int) (y, m, d int) {
+	y = floorDiv(30*(rd-islamicEpoch)+10646, 10631)
+	priorDays := rd - FixedFromHijri(y, 1, 1)
+	m = floorDiv(11*priorDays+330, 325)
+	d = rd - FixedFromHijri(y, m, 1) + 1
+	return y, m, d
+}
+
+// hebrewEpoch is the fixed day of 1 Tishri AM 1 (October 7, 3761 BCE Julian).
+const hebrewEpoch = -1373427
+
+// HebrewLeapYear reports whether year has the extra month Adar II.
+func HebrewLeapYear(y int) bool {
+	return floorMod(7*y+1, 19) < 7
+}
+
+func lastMonthOfHebrewYear(y int) int {
+	if HebrewLeapYear(y) {
+		return AdarII
+	}
+	return Adar
+}
+
+func hebrewCalendarElapsedDays(y int) int {
+	monthsElapsed := floorDiv(235*y-234, 19)
+	partsElapsed := 12084 + 13753*monthsElapsed
+	days := 29*monthsElapsed + floorDiv(partsElapsed, 25920)
+	if floorMod(3*(days+1), 7) < 3 {
+		return days + 1
+	}
+	return days
+}
+
+func hebrewYearLengthCorrection(y int) int {
+	ny0 := hebrewCalendarElapsedDays(y - 1)
+	ny1 := hebrewCalendarElapsedDays(y)
+	ny2 := hebrewCalendarElapsedDays(y + 1)
+	switch {
+	case ny2-ny1 == 356:
+		return 2
+	case ny1-ny0 == 382:
+		return 1
+	}
+	return 0
+}
+
+func hebrewNewYear(y int) int {
+	return hebrewEpoch + hebrewCalendarElapsedDays(y) + hebrewYearLengthCorrection(y)
+}
+
+func daysInHebrewYear(y int) int {
+	return hebrewNewYear(y+1) - hebrewNewYear(y)
+}
+
+func lastDayOfHebrewMonth(y, m int) int {
+	switch m {
+	case Iyyar, Tammuz, Elul, Tevet, AdarII:
+		return 29
+	case Adar:
+		if !HebrewLeapYear(y) {
+			return 29
+		}
+	case Marheshvan:
+		// Marheshvan is long only in 355- and 385-day years.
+		if daysInHebrewYear(y)%10 != 5 {
+			return 29
+		}
+	case Kislev:
+		// Kislev is short in 353- and 383-day years.
+		if daysInHebrewYear(y)%10 == 3 {
+			return 29
+		}
+	}
+	return 30
+}
+
+// FixedFromHebrew converts a Hebrew date. Month numbering starts at Nisan;
+// AdarII is treated as Adar in common (non-leap) years.
+func FixedFromHebrew(y, m, d int) int {
+	if m == AdarII && !HebrewLeapYear(y) {
+		m = Adar
+	}
+	rd := hebrewNewYear(y) + d - 1
+	if m < Tishri {
+		for mm := Tishri; mm <= lastMonthOfHebrewYear(y); mm++ {
+			rd += lastDayOfHebrewMonth(y, mm)
+		}
+		for mm := Nisan; mm < m; mm++ {
+			rd += lastDayOfHebrewMonth(y, mm)
+		}
+	} else {
+		for mm := Tishri; mm < m; mm++ {
+			rd += lastDayOfHebrewMonth(y, mm)
+		}
+	}
+	return rd
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+func floorMod(a, b int) int {
+	return a - b*floorDiv(a, b)
+}
@@ -0,0 +1,118 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestEaster(t *testing.T) {
+	for _, tc := range []struct {
+		year int
+		want string
+	}{
+		{2000, "2000-04-23"},
+		{2008, "2008-03-23"}, // early
+		{2019, "2019-04-21"},
+		{2024, "2024-03-31"},
+		{2025, "2025-04-20"},
+		{2026, "2026-04-05"},
+		{2038, "2038-04-25"}, // latest possible
+	} {
+		if got := Easter(tc.year).Format("2006-01-02"); got != tc.want {
+			t.Errorf("Easter(%d) = %s, want %s", tc.year, got, tc.want)
+		}
+	}
+}
+
+func TestHijri(t *testing.T) {
+	for _, tc := range []struct {
+		y, m, d int
+		want    string
+	}{
+		{1445, 9, 1, "2024-03-11"},   // 1 Ramadan
+		{1445, 12, 10, "2024-06-17"}, // Eid al-Adha
+		{1446, 1, 1, "2024-07-08"},   // 1 Muharram
+		{1446, 9, 1, "2025-03-01"},   // 1 Ramadan
+		{1446, 10, 1, "2025-03-31"},  // Eid al-Fitr
+	} {
+		rd := FixedFromHijri(tc.y, tc.m, tc.d)
+		if got := TimeFromFixed(rd).Format("2006-01-02"); got != tc.want {
+			t.Errorf("FixedFromHijri(%d, %d, %d) = %s, want %s", tc.y, tc.m, tc.d, got, tc.want)
+		}
+		if y, m, d := HijriFromFixed(rd); y != tc.y || m != tc.m || d != tc.d {
+			t.Errorf("HijriFromFixed(%s) = %d-%d-%d, want %d-%d-%d", tc.want, y, m, d, tc.y, tc.m, tc.d)
+		}
+	}
+}
+
+func TestHijriRoundTrip(t *testing.T) {
+	start := FixedFromTime(date("2020-01-01"))
+	for rd := start; rd < start+3*366; rd++ {
+		y, m, d := HijriFromFixed(rd)
+		if got := FixedFromHijri(y, m, d); got != rd {
+			t.Fatalf("%s: Hijri %d-%d-%d converts back to %s", TimeFromFixed(rd).Format("2006-01-02"),
+				y, m, d, TimeFromFixed(got).Format("2006-01-02"))
+		}
+	}
+}
+
+func TestHebrew(t *testing.T) {
+	for _, tc := range []struct {
+		y, m, d int
+		want    string
+	}{
+		{5784, Nisan, 15, "2024-04-23"},  // Passover
+		{5784, AdarII, 14, "2024-03-24"}, // Purim in a leap year
+		{5785, Tishri, 1, "2024-10-03"},  // Rosh Hashanah
+		{5785, Tishri, 10, "2024-10-12"}, // Yom Kippur
+		{5785, Kislev, 25, "2024-12-26"}, // Hanukkah
+		{5785, Adar, 14, "2025-03-14"},   // Purim
+		{5785, AdarII, 14, "2025-03-14"}, // Adar II is Adar in a common year
+		{5785, Nisan, 15, "2025-04-13"},  // Passover
+		{5786, Tishri, 1, "2025-09-23"},  // Rosh Hashanah
+	} {
+		if got := TimeFromFixed(FixedFromHebrew(tc.y, tc.m, tc.d)).Format("2006-01-02"); got != tc.want {
+			t.Errorf("FixedFromHebrew(%d, %d, %d) = %s, want %s", tc.y, tc.m, tc.d, got, tc.want)
+		}
+	}
+	for y, leap := range map[int]bool{5784: true, 5785: false, 5786: false, 5787: true} {
+		if HebrewLeapYear(y) != leap {
+			t.Errorf("HebrewLeapYear(%d) = %v, want %v", y, !leap, leap)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	rules := []Rule{
+		{ID: 1, Name: "Ramadan", Calendar: Hijri, Month: 9, Day: 1, Duration: 30, Topic: "patience", Priority: 10},
+		{ID: 2, Name: "Good Friday", Calendar: EasterSunday, Offset: -2, Duration: 1, Topic: "sacrifice", Priority: 20},
+		{ID: 3, Name: "Yom Kippur", Calendar: Hebrew, Month: Tishri, Day: 10, Duration: 1, Topic: "forgiveness", Priority: 20},
+	}
+	for _, tc := range []struct {
+		day, want string
+	}{
+		{"2025-02-28", ""},
+		{"2025-03-01", "Ramadan"},
+		{"2025-03-30", "Ramadan"},
+		{"2025-03-31", ""},
+		{"2025-04-18", "Good Friday"},
+		{"2024-10-12", "Yom Kippur"},
+		{"2024-03-29", "Good Friday"}, // inside Ramadan 1445; higher priority wins
+	} {
+		got := ""
+		if r := Select(rules, date(tc.day)); r != nil {
+			got = r.Name
+		}
+		if got != tc.want {
+			t.Errorf("Select(%s) = %q, want %q", tc.day, got, tc.want)
+		}
+	}
+}
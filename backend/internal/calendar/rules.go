@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Calendar systems a Rule can be anchored to.
+const (
+	Gregorian = "gregorian"
+	Hijri     = "hijri"
+	Hebrew    = "hebrew"
+	// EasterSunday anchors a rule to Western Easter; Month and Day are
+	// ignored and Offset moves to related feasts (e.g. -2 for Good Friday).
+	EasterSunday = "easter"
+)
+
+// Rule ties a recurring observance to a topic. The observance starts on
+// Month/Day of Calendar (shifted by Offset days) and lasts Duration days.
+// When several rules match a date the highest Priority wins.
+type Rule struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Calendar string `json:"calendar"`
+	Month    int    `json:"month"`
+	Day      int    `json:"day"`
+	Offset   int    `json:"offset_days"`
+	Duration int    `json:"duration_days"`
+	Topic    string `json:"topic"`
+	Priority int    `json:"priority"`
+}
+
+// Validate checks that the rule can be resolved to dates.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Topic == "" {
+		return fmt.Errorf("topic is required")
+	}
+	if r.Duration < 0 {
+		return fmt.Errorf("duration_days must not be negative")
+	}
+	switch r.Calendar {
+	case EasterSunday:
+		return nil
+	case Gregorian:
+		if r.Month < 1 || r.Month > 12 {
+			return fmt.Errorf("month must be 1-12 for the gregorian calendar")
+		}
+	case Hijri:
+		if r.Month < 1 || r.Month > 12 {
+			return fmt.Errorf("month must be 1-12 for the hijri calendar")
+		}
+	case Hebrew:
+		if r.Month < Nisan || r.Month > AdarII {
+			return fmt.Errorf("month must be 1 (Nisan) to 13 (Adar II) for the hebrew calendar")
+		}
+	default:
+		return fmt.Errorf("unknown calendar %q", r.Calendar)
+	}
+	if r.Day < 1 || r.Day > 31 {
+		return fmt.Errorf("day must be 1-31")
+	}
+	return nil
+}
+
+// Starts returns the fixed days on which the observance begins whose
+// anchor date falls in the given Gregorian year.
+func (r Rule) Starts(year int) []int {
+	var anchors []int
+	switch r.Calendar {
+	case Gregorian:
+		anchors = []int{fixedFromGregorian(year, r.Month, r.Day)}
+	case EasterSunday:
+		anchors = []int{FixedFromTime(Easter(year))}
+	case Hijri:
+		// A Gregorian year overlaps up to three Hijri years and can
+		// contain the same Hijri date twice.
+		first, _, _ := HijriFromFixed(fixedFromGregorian(year, 1, 1))
+		for hy := first; hy <= first+2; hy++ {
+			anchors = append(anchors, FixedFromHijri(hy, r.Month, r.Day))
+		}
+	case Hebrew:
+		for hy := year + 3760; hy <= year+3761; hy++ {
+			anchors = append(anchors, FixedFromHebrew(hy, r.Month, r.Day))
+		}
+	}
+
+	jan1, dec31 := fixedFromGregorian(year, 1, 1), fixedFromGregorian(year, 12, 31)
+	var out []int
+	for _, a := range anchors {
+		if a >= jan1 && a <= dec31 {
+			out = append(out, a+r.Offset)
+		}
+	}
+	return out
+}
+
+// Matches reports whether the observance is in effect on day.
+func (r Rule) Matches(day time.Time) bool {
+	rd := FixedFromTime(day)
+	length := r.Duration
+	if length < 1 {
+		length = 1
+	}
+	// Observances near New Year, or with large offsets, may be anchored in
+	// the neighbouring Gregorian year.
+	for y := day.Year() - 1; y <= day.Year()+1; y++ {
+		for _, start := range r.Starts(y) {
+			if rd >= start && rd < start+length {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Select returns the highest-priority rule in effect on day, or nil. Ties
+// go to the rule with the lowest ID so the choice is stable.
+func Select(rules []Rule, day time.Time) *Rule {
+	var matched []Rule
+	for _, r := range rules {
+		if r.Matches(day) {
+			matched = append(matched, r)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if matched[i].Priority != matched[j].Priority {
+			return matched[i].Priority > matched[j].Priority
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	return &matched[0]
+}
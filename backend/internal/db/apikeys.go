@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/your/module/internal/auth"
+)
+
+// APIKey is a stored key. The key itself is never stored, only its hash.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Role       auth.Role  `json:"role"`
+	Prefix     string     `json:"prefix"`
+	Hash       string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+const apiKeyColumns = `id, name, role, prefix, key_hash, created_at, last_used_at, revoked_at`
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var k APIKey
+	if err := row.Scan(&k.ID, &k.Name, &k.Role, &k.Prefix, &k.Hash, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func CreateAPIKey(dbh *sql.DB, name string, role auth.Role, prefix, hash string) (int, error) {
+	var id int
+	err := dbh.QueryRow(`INSERT INTO api_keys (name, role, prefix, key_hash) VALUES ($1,$2,$3,$4) RETURNING id`,
+		name, string(role), prefix, hash).Scan(&id)
+	return id, err
+}
+
+// GetActiveAPIKey returns the unrevoked key with prefix, or a "not_found"
+// error.
+func GetActiveAPIKey(dbh *sql.DB, prefix string) (*APIKey, error) {
+	k, err := scanAPIKey(dbh.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE prefix=$1 AND revoked_at IS NULL`, prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("not_found")
+	}
+	return k, err
+}
+
+func ListAPIKeys(dbh *sql.DB) ([]APIKey, error) {
+	rows, err := dbh.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *k)
+	}
+	return out, rows.Err()
+}
+
+// RevokeAPIKey revokes the key with the given ID or prefix. It returns a
+// "not_found" error if there is no such active key.
+func RevokeAPIKey(dbh *sql.DB, idOrPrefix string) error {
+	res, err := dbh.Exec(`UPDATE api_keys SET revoked_at=CURRENT_TIMESTAMP
+        WHERE (id::text = $1 OR prefix = $1) AND revoked_at IS NULL`, idOrPrefix)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("not_found")
+	}
+	return nil
+}
+
+// TouchAPIKey records that a key was used, at most once a minute.
+func TouchAPIKey(dbh *sql.DB, id int) error {
+	_, err := dbh.Exec(`UPDATE api_keys SET last_used_at=CURRENT_TIMESTAMP
+        WHERE id=$1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')`, id)
+	return err
+}
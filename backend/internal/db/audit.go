@@ -0,0 +1,75 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry records one content change made through the admin API.
+type AuditEntry struct {
+	ID        int             `json:"id"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"` // create, update, delete, tag, rename_topic, remove_topic, comp, uncomp
+	Entity    string          `json:"entity"` // verse, topic, subscriber
+	EntityID  string          `json:"entity_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// writeAudit records a change inside the transaction that makes it. before
+// and after are stored as JSON; pass nil for a side that does not exist.
+func writeAudit(tx *sql.Tx, actor, action, entity, entityID string, before, after any) error {
+	var b, a []byte
+	var err error
+	if before != nil {
+		if b, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if a, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+	_, err = tx.Exec(`INSERT INTO audit_log (actor, action, entity, entity_id, before_json, after_json)
+        VALUES ($1,$2,$3,$4,$5::jsonb,$6::jsonb)`, actor, action, entity, entityID, nullJSON(b), nullJSON(a))
+	return err
+}
+
+func nullJSON(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
+// ListAudit returns the newest audit entries first. entity and entityID
+// narrow the result when non-empty.
+func ListAudit(dbh *sql.DB, entity, entityID string, limit int) ([]AuditEntry, error) {
+	rows, err := dbh.Query(`SELECT id, actor, action, entity, entity_id, before_json, after_json, created_at
+        FROM audit_log
+        WHERE ($1 = '' OR entity = $1) AND ($2 = '' OR entity_id = $2)
+        ORDER BY id DESC LIMIT $3`, entity, entityID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Entity, &e.EntityID, &before, &after, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
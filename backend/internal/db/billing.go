@@ -0,0 +1,157 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Billing statuses of a subscriber, driven by payment provider webhooks.
+const (
+	BillingNone     = "none"     // never paid
+	BillingTrialing = "trialing" // in a free trial
+	BillingActive   = "active"   // latest invoice paid
+	BillingPastDue  = "past_due" // latest payment failed; the provider is retrying
+	BillingCanceled = "canceled" // subscription ended
+)
+
+// Billing is a subscriber's paid subscription state.
+type Billing struct {
+	Status string `json:"status"`
+	// PeriodEnd is when the current paid or trial period ends.
+	PeriodEnd *time.Time `json:"period_end,omitempty"`
+	// PastDueSince is when the status became past_due; the grace period
+	// runs from here.
+	PastDueSince *time.Time `json:"past_due_since,omitempty"`
+	// Comped subscribers are entitled without paying, until CompUntil if
+	// it is set.
+	Comped    bool       `json:"comped"`
+	CompUntil *time.Time `json:"comp_until,omitempty"`
+}
+
+// Entitled reports whether the subscriber may receive paid features at now.
+// Paid and trial periods are honoured for grace after they end, to allow
+// for a late renewal webhook, and past-due accounts keep access for grace
+// after the first failed payment. Canceled subscriptions last until the
+// end of the period already paid for.
+func (b Billing) Entitled(now time.Time, grace time.Duration) bool {
+	if b.Comped && (b.CompUntil == nil || now.Before(*b.CompUntil)) {
+		return true
+	}
+	switch b.Status {
+	case BillingActive, BillingTrialing:
+		return b.PeriodEnd == nil || now.Before(b.PeriodEnd.Add(grace))
+	case BillingPastDue:
+		return b.PastDueSince == nil || now.Before(b.PastDueSince.Add(grace))
+	case BillingCanceled:
+		return b.PeriodEnd != nil && now.Before(*b.PeriodEnd)
+	}
+	return false
+}
+
+// ClaimPaymentEvent records a payment provider webhook event before it is
+// handled. It returns false if the event was seen before, since providers
+// may deliver an event more than once. Events of every provider share the
+// stripe_events table, which predates the others.
+func ClaimPaymentEvent(dbh *sql.DB, id, eventType string) (bool, error) {
+	res, err := dbh.Exec(`INSERT INTO stripe_events(id, type) VALUES($1,$2) ON CONFLICT(id) DO NOTHING`, id, eventType)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReleasePaymentEvent forgets an event whose handling failed, so the
+// provider's retry is processed rather than skipped as a duplicate.
+func ReleasePaymentEvent(dbh *sql.DB, id string) error {
+	_, err := dbh.Exec(`DELETE FROM stripe_events WHERE id=$1`, id)
+	return err
+}
+
+// LinkCustomer attaches the payment provider's customer and subscription
+// to a subscriber after checkout. Billing status is left to
+// SetBillingStatus, since a completed checkout is not necessarily paid.
+// The stripe_* columns hold the IDs of whichever provider is configured.
+func LinkCustomer(dbh *sql.DB, subscriberID int, customerID, subscriptionID string) error {
+	_, err := dbh.Exec(`UPDATE subscribers SET stripe_customer_id=$2, stripe_subscription_id=$3 WHERE id=$1`,
+		subscriberID, customerID, subscriptionID)
+	return err
+}
+
+// GetSubscriptionID returns the provider subscription of a subscriber, or
+// "" if they never checked out.
+func GetSubscriptionID(dbh *sql.DB, subscriberID int) (string, error) {
+	var id string
+	err := dbh.QueryRow(`SELECT stripe_subscription_id FROM subscribers WHERE id=$1`, subscriberID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errors.New("not_found")
+	}
+	return id, err
+}
+
+// SetBillingStatus applies a provider event created at `at` to the
+// subscriber with the given provider subscription, or failing that
+// customer. A nil periodEnd keeps the stored one. Events older than the
+// last one applied are skipped, as providers may deliver them out of
+// order. It returns how many subscribers matched and how many of those
+// were updated.
+func SetBillingStatus(dbh *sql.DB, customerID, subscriptionID, status string, periodEnd *time.Time, at time.Time) (matched, applied int64, err error) {
+	const match = `(($2 <> '' AND stripe_subscription_id=$2) OR ($1 <> '' AND stripe_customer_id=$1))`
+	res, err := dbh.Exec(`UPDATE subscribers SET billing_status=$3,
+            billing_period_end=COALESCE($4, billing_period_end),
+            past_due_since=CASE WHEN $3='past_due' THEN COALESCE(past_due_since, CURRENT_TIMESTAMP) END,
+            billing_event_at=$5
+        WHERE `+match+` AND (billing_event_at IS NULL OR billing_event_at <= $5)`,
+		customerID, subscriptionID, status, periodEnd, at)
+	if err != nil {
+		return 0, 0, err
+	}
+	if applied, err = res.RowsAffected(); err != nil || applied > 0 {
+		return applied, applied, err
+	}
+	err = dbh.QueryRow(`SELECT count(*) FROM subscribers WHERE `+match, customerID, subscriptionID).Scan(&matched)
+	return matched, 0, err
+}
+
+// SetComp grants (comped true) or removes a complimentary subscription and
+// records the change in the audit log. until limits the comp; nil means
+// indefinitely.
+func SetComp(dbh *sql.DB, actor string, subscriberID int, comped bool, until *time.Time, note string) error {
+	tx, err := dbh.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before Billing
+	err = tx.QueryRow(`SELECT comped, comp_until FROM subscribers WHERE id=$1 FOR UPDATE`, subscriberID).
+		Scan(&before.Comped, &before.CompUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errors.New("not_found")
+	}
+	if err != nil {
+		return err
+	}
+	if !comped {
+		until = nil
+	}
+	if _, err := tx.Exec(`UPDATE subscribers SET comped=$2, comp_until=$3 WHERE id=$1`, subscriberID, comped, until); err != nil {
+		return err
+	}
+	type comp struct {
+		Comped    bool       `json:"comped"`
+		CompUntil *time.Time `json:"comp_until,omitempty"`
+		Note      string     `json:"note,omitempty"`
+	}
+	action := "comp"
+	if !comped {
+		action = "uncomp"
+	}
+	if err := writeAudit(tx, actor, action, "subscriber", strconv.Itoa(subscriberID),
+		comp{Comped: before.Comped, CompUntil: before.CompUntil}, comp{Comped: comped, CompUntil: until, Note: note}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -5,20 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
-)
 
-type Daily struct {
-	Date    string                 `json:"date"`
-	Area    string                 `json:"area"`
-	Quran   map[string]string      `json:"quran"`
-	Torah   map[string]string      `json:"torah"`
-	Bible   map[string]string      `json:"bible"`
-	HD      map[string]string      `json:"human_design"`
-	Summary string                 `json:"summary"`
-	Meta    map[string]interface{} `json:"meta,omitempty"`
-}
+	"github.com/your/module/internal/scripture"
+)
 
 func Connect(dsn string) *sql.DB {
 	db, err := sql.Open("pgx", dsn)
@@ -32,9 +26,36 @@ func Connect(dsn string) *sql.DB {
 func ensureMigrated(db *sql.DB) {
 	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS daily_payloads (
         date TEXT PRIMARY KEY,
-        payload_json TEXT NOT NULL,
-        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+        payload_json JSONB NOT NULL,
+        schema_version INTEGER NOT NULL DEFAULT 1,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );`)
+	// Upgrade tables created when payload_json was opaque TEXT.
+	_, _ = db.Exec(`DO $$ BEGIN
+        IF (SELECT data_type FROM information_schema.columns
+            WHERE table_name = 'daily_payloads' AND column_name = 'payload_json') = 'text' THEN
+            ALTER TABLE daily_payloads ALTER COLUMN payload_json TYPE JSONB USING payload_json::jsonb;
+        END IF;
+    END $$;`)
+	_, _ = db.Exec(`ALTER TABLE daily_payloads ADD COLUMN IF NOT EXISTS schema_version INTEGER NOT NULL DEFAULT 1`)
+	_, _ = db.Exec(`ALTER TABLE daily_payloads ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS daily_payload_revisions (
+        id SERIAL PRIMARY KEY,
+        date TEXT NOT NULL,
+        revision INTEGER NOT NULL,
+        schema_version INTEGER NOT NULL,
+        payload_json JSONB NOT NULL,
+        author TEXT NOT NULL,
+        reason TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        UNIQUE (date, revision)
     );`)
+	// Payloads written before revisions were kept become revision 1.
+	_, _ = db.Exec(`INSERT INTO daily_payload_revisions (date, revision, schema_version, payload_json, author, reason, created_at)
+        SELECT p.date, 1, p.schema_version, p.payload_json, 'migration', 'existing payload', p.created_at
+        FROM daily_payloads p
+        WHERE NOT EXISTS (SELECT 1 FROM daily_payload_revisions r WHERE r.date = p.date)`)
 	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS visitor_stats (
         id INTEGER PRIMARY KEY CHECK (id = 1),
         count INTEGER NOT NULL DEFAULT 0
@@ -46,10 +67,212 @@ func ensureMigrated(db *sql.DB) {
         text TEXT NOT NULL,
         topics TEXT NOT NULL          -- comma-separated topics
     );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS schedule_overrides (
+        date TEXT PRIMARY KEY,
+        topic TEXT NOT NULL,
+        verse_ids TEXT NOT NULL DEFAULT '',  -- comma-separated verses.id values
+        note TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS calendar_rules (
+        id SERIAL PRIMARY KEY,
+        name TEXT NOT NULL,
+        calendar TEXT NOT NULL,       -- 'gregorian', 'hijri', 'hebrew', 'easter'
+        month INTEGER NOT NULL DEFAULT 0,
+        day INTEGER NOT NULL DEFAULT 0,
+        offset_days INTEGER NOT NULL DEFAULT 0,
+        duration_days INTEGER NOT NULL DEFAULT 1,
+        topic TEXT NOT NULL,
+        priority INTEGER NOT NULL DEFAULT 0
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS subscribers (
+        id SERIAL PRIMARY KEY,
+        full_name TEXT NOT NULL,
+        email TEXT NOT NULL UNIQUE,
+        phone TEXT NOT NULL DEFAULT '',
+        address TEXT NOT NULL DEFAULT '',
+        city TEXT NOT NULL DEFAULT '',
+        country TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        unsubscribed_at TIMESTAMPTZ
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS deliveries (
+        date TEXT NOT NULL,
+        subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+        status TEXT NOT NULL,         -- 'pending', 'sent', 'failed', 'bounced'
+        error TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        finished_at TIMESTAMPTZ,
+        PRIMARY KEY (date, subscriber_id)
+    );`)
+	_, _ = db.Exec(`ALTER TABLE subscribers ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC'`)
+	_, _ = db.Exec(`ALTER TABLE subscribers
+        ADD COLUMN IF NOT EXISTS sources TEXT NOT NULL DEFAULT '',          -- comma-separated; '' means all
+        ADD COLUMN IF NOT EXISTS frequency TEXT NOT NULL DEFAULT 'daily',   -- daily, weekdays, weekly
+        ADD COLUMN IF NOT EXISTS digest_weekday INTEGER NOT NULL DEFAULT 0, -- 0 = Sunday
+        ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en',
+        ADD COLUMN IF NOT EXISTS delivery_hour INTEGER                       -- NULL uses DELIVERY_HOUR
+    `)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS verse_translations (
+        source TEXT NOT NULL,
+        ref TEXT NOT NULL,
+        language TEXT NOT NULL,
+        text TEXT NOT NULL,
+        PRIMARY KEY (source, ref, language)
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS sources (
+        id TEXT PRIMARY KEY,          -- matches verses.source
+        name TEXT NOT NULL,
+        position INTEGER NOT NULL DEFAULT 0,
+        citation_style TEXT NOT NULL DEFAULT '{ref}',
+        enabled BOOLEAN NOT NULL DEFAULT TRUE
+    );`)
+	ensureDefaultSources(db)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS summary_templates (
+        id SERIAL PRIMARY KEY,
+        topic TEXT NOT NULL DEFAULT '',   -- '' applies to every topic
+        body TEXT NOT NULL,               -- Go text/template, see internal/summary
+        enabled BOOLEAN NOT NULL DEFAULT TRUE
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS topic_blurbs (
+        id SERIAL PRIMARY KEY,
+        topic TEXT NOT NULL,
+        body TEXT NOT NULL
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS generated_summaries (
+        date TEXT NOT NULL,
+        input_hash TEXT NOT NULL,         -- provider + topic + passages
+        provider TEXT NOT NULL,
+        text TEXT NOT NULL,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        PRIMARY KEY (date, input_hash)
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS audit_log (
+        id SERIAL PRIMARY KEY,
+        actor TEXT NOT NULL,
+        action TEXT NOT NULL,
+        entity TEXT NOT NULL,
+        entity_id TEXT NOT NULL,
+        before_json JSONB,
+        after_json JSONB,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON audit_log (entity, entity_id);`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS api_keys (
+        id SERIAL PRIMARY KEY,
+        name TEXT NOT NULL,
+        role TEXT NOT NULL,               -- reader, editor, sender, admin
+        prefix TEXT NOT NULL UNIQUE,      -- public part of the key, for lookup
+        key_hash TEXT NOT NULL,           -- SHA-256 of the whole key
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        last_used_at TIMESTAMPTZ,
+        revoked_at TIMESTAMPTZ
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS login_tokens (
+        token_hash TEXT PRIMARY KEY,      -- SHA-256 of the emailed token
+        subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMPTZ NOT NULL,
+        used_at TIMESTAMPTZ
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
+        token_hash TEXT PRIMARY KEY,      -- SHA-256 of the cookie value
+        subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        expires_at TIMESTAMPTZ NOT NULL
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS digest_deliveries (
+        week_ending TEXT NOT NULL,
+        subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+        status TEXT NOT NULL,         -- 'pending', 'sent', 'failed', 'bounced'
+        error TEXT NOT NULL DEFAULT '',
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+        finished_at TIMESTAMPTZ,
+        PRIMARY KEY (week_ending, subscriber_id)
+    );`)
+	_, _ = db.Exec(`ALTER TABLE subscribers
+        ADD COLUMN IF NOT EXISTS stripe_customer_id TEXT NOT NULL DEFAULT '',
+        ADD COLUMN IF NOT EXISTS stripe_subscription_id TEXT NOT NULL DEFAULT '',
+        ADD COLUMN IF NOT EXISTS billing_status TEXT NOT NULL DEFAULT 'none' -- none, active, past_due, canceled
+    `)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS stripe_events (
+        id TEXT PRIMARY KEY,
+        type TEXT NOT NULL,
+        received_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );`)
+	_, _ = db.Exec(`ALTER TABLE subscribers
+        ADD COLUMN IF NOT EXISTS billing_period_end TIMESTAMPTZ,
+        ADD COLUMN IF NOT EXISTS past_due_since TIMESTAMPTZ,
+        ADD COLUMN IF NOT EXISTS comped BOOLEAN NOT NULL DEFAULT false,
+        ADD COLUMN IF NOT EXISTS comp_until TIMESTAMPTZ -- NULL with comped means indefinitely
+    `)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS visitor_daily (
+        day TEXT NOT NULL,
+        endpoint TEXT NOT NULL,
+        hits INTEGER NOT NULL DEFAULT 0,
+        uniques INTEGER,              -- filled in when the day is compacted
+        PRIMARY KEY (day, endpoint)
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS visitor_uniques (
+        day TEXT NOT NULL,
+        endpoint TEXT NOT NULL,
+        visitor TEXT NOT NULL,        -- salted hash of IP and User-Agent
+        PRIMARY KEY (day, endpoint, visitor)
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS visitor_salts (
+        day TEXT PRIMARY KEY,
+        salt TEXT NOT NULL            -- '' once the day is compacted
+    );`)
+	_, _ = db.Exec(`ALTER TABLE subscribers ADD COLUMN IF NOT EXISTS no_tracking BOOLEAN NOT NULL DEFAULT FALSE`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS email_messages (
+        token TEXT PRIMARY KEY,       -- random, in the pixel and link URLs
+        date TEXT NOT NULL,
+        subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+        topic TEXT NOT NULL,
+        sent_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS email_messages_date_idx ON email_messages (date);`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS email_links (
+        token TEXT NOT NULL REFERENCES email_messages(token) ON DELETE CASCADE,
+        n INTEGER NOT NULL,
+        url TEXT NOT NULL,
+        PRIMARY KEY (token, n)
+    );`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS email_events (
+        id SERIAL PRIMARY KEY,
+        token TEXT NOT NULL REFERENCES email_messages(token) ON DELETE CASCADE,
+        kind TEXT NOT NULL,           -- 'open' or 'click'
+        link INTEGER,                 -- the clicked link's n
+        created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+    );`)
+	_, _ = db.Exec(`CREATE INDEX IF NOT EXISTS email_events_token_idx ON email_events (token);`)
+	// confirmed_at was renamed first_sign_in_at, as a sign-in does not
+	// confirm the subscription; the rename fails harmlessly once done.
+	_, _ = db.Exec(`ALTER TABLE subscribers RENAME COLUMN confirmed_at TO first_sign_in_at`)
+	_, _ = db.Exec(`ALTER TABLE subscribers ADD COLUMN IF NOT EXISTS first_sign_in_at TIMESTAMPTZ`)
+	_, _ = db.Exec(`UPDATE subscribers s SET first_sign_in_at = t.first_used
+        FROM (SELECT subscriber_id, MIN(used_at) AS first_used FROM login_tokens WHERE used_at IS NOT NULL GROUP BY subscriber_id) t
+        WHERE s.id = t.subscriber_id AND s.first_sign_in_at IS NULL`)
+	_, _ = db.Exec(`CREATE TABLE IF NOT EXISTS delivery_runs (
+        id SERIAL PRIMARY KEY,
+        kind TEXT NOT NULL,           -- 'daily' or 'digest'
+        started_at TIMESTAMPTZ NOT NULL,
+        finished_at TIMESTAMPTZ NOT NULL,
+        sent INTEGER NOT NULL,
+        failed INTEGER NOT NULL,
+        bounced INTEGER NOT NULL
+    );`)
+	_, _ = db.Exec(`ALTER TABLE subscribers
+        ADD COLUMN IF NOT EXISTS billing_event_at TIMESTAMPTZ -- creation time of the last payment event applied
+    `)
+	_, _ = db.Exec(`ALTER TABLE email_messages
+        ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'sent' -- sending, sent, failed or bounced
+    `)
 	EnsureVisitorStats(db)
 }
 
-func GetDailyPayloadDate(dbh *sql.DB, date string) (*Daily, error) {
+func GetDailyPayloadDate(dbh *sql.DB, date string) (*scripture.Daily, error) {
 	var js string
 	err := dbh.QueryRow(`SELECT payload_json FROM daily_payloads WHERE date=$1`, date).Scan(&js)
 	if err != nil {
@@ -58,26 +281,98 @@ func GetDailyPayloadDate(dbh *sql.DB, date string) (*Daily, error) {
 		}
 		return nil, err
 	}
-	var d Daily
+	var d scripture.Daily
 	if err := json.Unmarshal([]byte(js), &d); err != nil {
 		return nil, err
 	}
 	return &d, nil
 }
 
+// SaveDailyPayload publishes d as the payload for d.Date and records it as
+// a new revision, so earlier versions remain available to restore. author
+// and reason describe who made the change and why.
+func SaveDailyPayload(dbh *sql.DB, d scripture.Daily, author, reason string) (int, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := dbh.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Serialize writers for the same date so revision numbers stay dense.
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext('daily_payload:' || $1))`, d.Date); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(`INSERT INTO daily_payloads(date, payload_json, schema_version) VALUES($1,$2::jsonb,$3)
+        ON CONFLICT(date) DO UPDATE SET payload_json=excluded.payload_json,
+            schema_version=excluded.schema_version, updated_at=CURRENT_TIMESTAMP`,
+		d.Date, string(b), scripture.SchemaVersion); err != nil {
+		return 0, err
+	}
+	var revision int
+	if err := tx.QueryRow(`INSERT INTO daily_payload_revisions (date, revision, schema_version, payload_json, author, reason)
+        SELECT $1, COALESCE(MAX(revision), 0) + 1, $3, $2::jsonb, $4, $5
+        FROM daily_payload_revisions WHERE date = $1
+        RETURNING revision`, d.Date, string(b), scripture.SchemaVersion, author, reason).Scan(&revision); err != nil {
+		return 0, err
+	}
+	return revision, tx.Commit()
+}
+
+func DailyPayloadExists(dbh *sql.DB, date string) (bool, error) {
+	var exists bool
+	err := dbh.QueryRow(`SELECT EXISTS(SELECT 1 FROM daily_payloads WHERE date=$1)`, date).Scan(&exists)
+	return exists, err
+}
+
+// PublishedPayload is a stored payload with when it was first published
+// and last changed.
+type PublishedPayload struct {
+	scripture.Daily
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ListRecentPayloads returns up to limit payloads dated from to to
+// (inclusive), newest first, limited to one topic unless topic is "".
+func ListRecentPayloads(dbh *sql.DB, from, to, topic string, limit int) ([]PublishedPayload, error) {
+	rows, err := dbh.Query(`SELECT payload_json, COALESCE(created_at, CURRENT_TIMESTAMP), COALESCE(updated_at, created_at, CURRENT_TIMESTAMP)
+        FROM daily_payloads
+        WHERE date >= $1 AND date <= $2 AND ($3 = '' OR LOWER(payload_json->>'area') = LOWER($3))
+        ORDER BY date DESC LIMIT $4`, from, to, topic, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PublishedPayload
+	for rows.Next() {
+		var js string
+		var p PublishedPayload
+		if err := rows.Scan(&js, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(js), &p.Daily); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
 func EnsureVisitorStats(db *sql.DB) {
 	_, _ = db.Exec(`INSERT INTO visitor_stats (id, count) VALUES (1, 0)
         ON CONFLICT (id) DO NOTHING;`)
 }
 
-func IncrementVisitorCount(db *sql.DB) error {
-	_, err := db.Exec(`UPDATE visitor_stats SET count = count + 1 WHERE id = 1;`)
-	return err
-}
-
+// GetVisitorCount is the all-time number of /api/today views: the old
+// global counter, which no longer changes, plus the daily counts since.
 func GetVisitorCount(db *sql.DB) (int, error) {
 	var count int
-	err := db.QueryRow(`SELECT count FROM visitor_stats WHERE id = 1;`).Scan(&count)
+	err := db.QueryRow(`SELECT (SELECT count FROM visitor_stats WHERE id = 1)
+            + COALESCE((SELECT SUM(hits) FROM visitor_daily WHERE endpoint = 'today'), 0)`).Scan(&count)
 	return count, err
 }
 
@@ -115,7 +410,7 @@ func SeedExampleVerses(db *sql.DB) {
 
 func GetRandomTopic(db *sql.DB) (string, error) {
 	var topic string
-	err := db.QueryRow(`SELECT topics FROM verses ORDER BY RANDOM() LIMIT 1`).Scan(&topic)
+	err := db.QueryRow(`SELECT topics FROM verses WHERE TRIM(topics) <> '' ORDER BY RANDOM() LIMIT 1`).Scan(&topic)
 	if err != nil {
 		return "generosity", nil // fallback
 	}
@@ -129,10 +424,59 @@ func GetRandomTopic(db *sql.DB) (string, error) {
 }
 
 func GetVerseByTopicAndSource(db *sql.DB, source, topic string) (ref, text string, err error) {
-	err = db.QueryRow(`SELECT ref, text FROM verses WHERE source = $1 AND topics LIKE $2 ORDER BY RANDOM() LIMIT 1`, source, "%"+topic+"%").Scan(&ref, &text)
+	err = db.QueryRow(`SELECT ref, text FROM verses WHERE source = $1 AND `+hasTopic("$2")+` ORDER BY RANDOM() LIMIT 1`, source, topic).Scan(&ref, &text)
 	return
 }
 
+// GetRandomVerseBySource returns any verse from source, regardless of topic.
+// It returns sql.ErrNoRows if the source has no verses at all.
+func GetRandomVerseBySource(db *sql.DB, source string) (ref, text string, err error) {
+	err = db.QueryRow(`SELECT ref, text FROM verses WHERE source = $1 ORDER BY RANDOM() LIMIT 1`, source).Scan(&ref, &text)
+	return
+}
+
+// CompleteTopics returns every topic that has at least one verse in each of
+// the given sources.
+func CompleteTopics(db *sql.DB, sources []string) ([]string, error) {
+	rows, err := db.Query(`SELECT source, topics FROM verses`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	have := map[string]map[string]bool{}
+	for rows.Next() {
+		var source, topics string
+		if err := rows.Scan(&source, &topics); err != nil {
+			return nil, err
+		}
+		for _, t := range strings.Split(topics, ",") {
+			t = strings.TrimSpace(t)
+			if t == "" {
+				continue
+			}
+			if have[t] == nil {
+				have[t] = map[string]bool{}
+			}
+			have[t][source] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var out []string
+	for t, got := range have {
+		complete := true
+		for _, s := range sources {
+			complete = complete && got[s]
+		}
+		if complete {
+			out = append(out, t)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
 // Helper for string index (since strings.Index not available in SQL)
 func stringIndex(s string, sep byte) int {
 	for i := 0; i < len(s); i++ {
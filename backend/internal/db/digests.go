@@ -0,0 +1,52 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// ListDailyPayloads returns the stored payloads dated from to to (inclusive),
+// oldest first. Dates without a payload are simply absent.
+func ListDailyPayloads(dbh *sql.DB, from, to string) ([]scripture.Daily, error) {
+	rows, err := dbh.Query(`SELECT payload_json FROM daily_payloads WHERE date >= $1 AND date <= $2 ORDER BY date`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []scripture.Daily
+	for rows.Next() {
+		var js string
+		if err := rows.Scan(&js); err != nil {
+			return nil, err
+		}
+		var d scripture.Daily
+		if err := json.Unmarshal([]byte(js), &d); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// ClaimDigest records that the weekly digest ending on weekEnding is about
+// to be sent to a subscriber. Like ClaimDelivery, it returns false if the
+// digest was already claimed.
+func ClaimDigest(dbh *sql.DB, weekEnding string, subscriberID int) (bool, error) {
+	res, err := dbh.Exec(`INSERT INTO digest_deliveries(week_ending, subscriber_id, status) VALUES($1,$2,'pending')
+        ON CONFLICT(week_ending, subscriber_id) DO NOTHING`, weekEnding, subscriberID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// FinishDigest records the outcome ('sent', 'failed' or 'bounced') of a
+// claimed digest.
+func FinishDigest(dbh *sql.DB, weekEnding string, subscriberID int, status, errMsg string) error {
+	_, err := dbh.Exec(`UPDATE digest_deliveries SET status=$3, error=$4, finished_at=CURRENT_TIMESTAMP
+        WHERE week_ending=$1 AND subscriber_id=$2`, weekEnding, subscriberID, status, errMsg)
+	return err
+}
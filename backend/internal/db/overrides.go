@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// ScheduleOverride pins a topic, and optionally exact verses, to a date.
+type ScheduleOverride struct {
+	Date      string    `json:"date"`
+	Topic     string    `json:"topic"`
+	VerseIDs  []int     `json:"verse_ids"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func GetVerseByID(dbh *sql.DB, id int) (*scripture.Verse, error) {
+	var v scripture.Verse
+	err := dbh.QueryRow(`SELECT id, source, ref, text, topics FROM verses WHERE id=$1`, id).
+		Scan(&v.ID, &v.Source, &v.Ref, &v.Text, &v.Topics)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// TopicExists reports whether any verse is tagged with topic.
+func TopicExists(dbh *sql.DB, topic string) (bool, error) {
+	var exists bool
+	err := dbh.QueryRow(`SELECT EXISTS(SELECT 1 FROM verses WHERE `+hasTopic("$1")+`)`, topic).Scan(&exists)
+	return exists, err
+}
+
+// hasTopic is a condition on verses that holds when the comma-separated
+// topics column contains the topic in param exactly, so "peace" does not
+// match "inner peace".
+func hasTopic(param string) string {
+	return `EXISTS (SELECT 1 FROM unnest(string_to_array(topics, ',')) AS t WHERE TRIM(t) = ` + param + `)`
+}
+
+// MissingVerseIDs returns the subset of ids that no longer exist in verses.
+func MissingVerseIDs(dbh *sql.DB, ids []int) ([]int, error) {
+	var missing []int
+	for _, id := range ids {
+		var exists bool
+		if err := dbh.QueryRow(`SELECT EXISTS(SELECT 1 FROM verses WHERE id=$1)`, id).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+func GetScheduleOverride(dbh *sql.DB, date string) (*ScheduleOverride, error) {
+	row := dbh.QueryRow(`SELECT date, topic, verse_ids, note, created_at, updated_at
+        FROM schedule_overrides WHERE date=$1`, date)
+	o, err := scanScheduleOverride(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	return o, nil
+}
+
+// ListScheduleOverrides returns overrides between from and to inclusive.
+// Empty bounds are open-ended.
+func ListScheduleOverrides(dbh *sql.DB, from, to string) ([]ScheduleOverride, error) {
+	rows, err := dbh.Query(`SELECT date, topic, verse_ids, note, created_at, updated_at
+        FROM schedule_overrides
+        WHERE ($1 = '' OR date >= $1) AND ($2 = '' OR date <= $2)
+        ORDER BY date`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ScheduleOverride
+	for rows.Next() {
+		o, err := scanScheduleOverride(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *o)
+	}
+	return out, rows.Err()
+}
+
+func UpsertScheduleOverride(dbh *sql.DB, o ScheduleOverride) error {
+	_, err := dbh.Exec(`INSERT INTO schedule_overrides(date, topic, verse_ids, note)
+        VALUES($1,$2,$3,$4)
+        ON CONFLICT(date) DO UPDATE SET topic=excluded.topic, verse_ids=excluded.verse_ids,
+            note=excluded.note, updated_at=CURRENT_TIMESTAMP`,
+		o.Date, o.Topic, joinIDs(o.VerseIDs), o.Note)
+	return err
+}
+
+func DeleteScheduleOverride(dbh *sql.DB, date string) error {
+	res, err := dbh.Exec(`DELETE FROM schedule_overrides WHERE date=$1`, date)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("not_found")
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanScheduleOverride(row rowScanner) (*ScheduleOverride, error) {
+	var o ScheduleOverride
+	var ids string
+	if err := row.Scan(&o.Date, &o.Topic, &ids, &o.Note, &o.CreatedAt, &o.UpdatedAt); err != nil {
+		return nil, err
+	}
+	o.VerseIDs = splitIDs(ids)
+	return &o, nil
+}
+
+// verse_ids is stored comma-separated, like verses.topics.
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func splitIDs(s string) []int {
+	ids := []int{}
+	for _, part := range strings.Split(s, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(part)); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
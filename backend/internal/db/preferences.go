@@ -0,0 +1,178 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// Delivery frequencies.
+const (
+	FrequencyDaily    = "daily"
+	FrequencyWeekdays = "weekdays" // Monday to Friday, local time
+	FrequencyWeekly   = "weekly"   // one digest on DigestWeekday
+)
+
+// Preferences control what a subscriber receives and when.
+type Preferences struct {
+	// Sources are the traditions to include, by registry id; empty means
+	// every source.
+	Sources       []string     `json:"sources"`
+	Frequency     string       `json:"frequency"`
+	DigestWeekday time.Weekday `json:"digest_weekday"`
+	// Language selects the email's wording and, where a translation
+	// exists, the verse text.
+	Language string `json:"language"`
+	// DeliveryHour is the local hour the email is due; nil uses the
+	// DELIVERY_HOUR default.
+	DeliveryHour *int `json:"delivery_hour"`
+	// NoTracking turns off open and click tracking in the subscriber's
+	// emails. Setting it also deletes what was already recorded.
+	NoTracking bool `json:"no_tracking"`
+}
+
+func (p Preferences) withDefaults() Preferences {
+	if p.Frequency == "" {
+		p.Frequency = FrequencyDaily
+	}
+	if p.Language == "" {
+		p.Language = "en"
+	}
+	return p
+}
+
+// Validate normalises p and checks it against the known source ids.
+func (p *Preferences) Validate(sources []Source) error {
+	*p = p.withDefaults()
+	known := map[string]bool{}
+	for _, s := range sources {
+		known[s.ID] = true
+	}
+	for _, id := range p.Sources {
+		if !known[id] {
+			return fmt.Errorf("unknown source %q", id)
+		}
+	}
+	switch p.Frequency {
+	case FrequencyDaily, FrequencyWeekdays, FrequencyWeekly:
+	default:
+		return fmt.Errorf("frequency must be daily, weekdays or weekly")
+	}
+	if p.DigestWeekday < time.Sunday || p.DigestWeekday > time.Saturday {
+		return fmt.Errorf("digest_weekday must be 0 (Sunday) to 6 (Saturday)")
+	}
+	p.Language = strings.ToLower(strings.TrimSpace(p.Language))
+	if len(p.Language) < 2 || len(p.Language) > 8 {
+		return fmt.Errorf("language must be a language code such as en or ms")
+	}
+	if p.DeliveryHour != nil && (*p.DeliveryHour < 0 || *p.DeliveryHour > 23) {
+		return fmt.Errorf("delivery_hour must be 0 to 23")
+	}
+	return nil
+}
+
+// Wants reports whether the subscriber's daily email is due on a local
+// date with the given weekday. Weekly subscribers only get the digest.
+func (p Preferences) Wants(day time.Weekday) bool {
+	switch p.Frequency {
+	case FrequencyWeekdays:
+		return day != time.Saturday && day != time.Sunday
+	case FrequencyWeekly:
+		return false
+	}
+	return true
+}
+
+// UpdatePreferences replaces a subscriber's preferences. Opting out of
+// tracking deletes the subscriber's recorded opens and clicks.
+func UpdatePreferences(dbh *sql.DB, id int, p Preferences) error {
+	p = p.withDefaults()
+	_, err := dbh.Exec(`UPDATE subscribers SET sources=$2, frequency=$3, digest_weekday=$4, language=$5, delivery_hour=$6,
+            no_tracking=$7
+        WHERE id=$1`, id, strings.Join(p.Sources, ","), p.Frequency, p.DigestWeekday, p.Language, p.DeliveryHour, p.NoTracking)
+	if err == nil && p.NoTracking {
+		err = DeleteEmailEvents(dbh, id)
+	}
+	return err
+}
+
+// OptOutOfTracking sets no_tracking for a subscriber and deletes their
+// recorded opens and clicks, as the opt-out link in tracked emails does.
+func OptOutOfTracking(dbh *sql.DB, id int) error {
+	res, err := dbh.Exec(`UPDATE subscribers SET no_tracking=true WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("not_found")
+	}
+	return DeleteEmailEvents(dbh, id)
+}
+
+// GetTranslations returns the text of each passage in language, keyed by
+// source, for the passages that have a translation.
+func GetTranslations(dbh *sql.DB, language string, passages []scripture.Passage) (map[string]string, error) {
+	out := map[string]string{}
+	for _, p := range passages {
+		var text string
+		err := dbh.QueryRow(`SELECT text FROM verse_translations WHERE source=$1 AND ref=$2 AND language=$3`,
+			p.Source, p.Ref, language).Scan(&text)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out[p.Source] = text
+	}
+	return out, nil
+}
+
+// UpsertTranslation stores the text of a verse in another language.
+func UpsertTranslation(dbh *sql.DB, t Translation) error {
+	_, err := dbh.Exec(`INSERT INTO verse_translations (source, ref, language, text) VALUES ($1,$2,$3,$4)
+        ON CONFLICT (source, ref, language) DO UPDATE SET text=excluded.text`, t.Source, t.Ref, t.Language, t.Text)
+	return err
+}
+
+// Translation is a verse's text in another language.
+type Translation struct {
+	Source   string `json:"source"`
+	Ref      string `json:"ref"`
+	Language string `json:"language"`
+	Text     string `json:"text"`
+}
+
+// ListTranslations returns the translations into language, or all of them
+// when language is empty.
+func ListTranslations(dbh *sql.DB, language string) ([]Translation, error) {
+	rows, err := dbh.Query(`SELECT source, ref, language, text FROM verse_translations
+        WHERE $1 = '' OR language = $1 ORDER BY language, source, ref`, language)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Translation
+	for rows.Next() {
+		var t Translation
+		if err := rows.Scan(&t.Source, &t.Ref, &t.Language, &t.Text); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
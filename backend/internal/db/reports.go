@@ -0,0 +1,121 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SignupDay summarises subscriber activity on one UTC day. Deleted
+// subscribers are not counted.
+type SignupDay struct {
+	Date    string `json:"date"`
+	Signups int    `json:"signups"`
+	// SignedIn counts the day's signups that have since used a sign-in
+	// link. It is not a confirmation: subscribers are not asked to sign
+	// in, so most never do.
+	SignedIn     int     `json:"signed_in"`
+	SignedInRate float64 `json:"signed_in_rate"`
+	Unsubscribes int     `json:"unsubscribes"`
+	// Bounces counts the daily emails for the date and digests for the
+	// week ending on it that the mail server rejected permanently.
+	Bounces int `json:"bounces"`
+}
+
+// ListSignupDays returns every day from from to to (inclusive), including
+// days with no activity.
+func ListSignupDays(dbh *sql.DB, from, to string) ([]SignupDay, error) {
+	rows, err := dbh.Query(`SELECT d::date::text,
+            (SELECT COUNT(*) FROM subscribers WHERE (created_at AT TIME ZONE 'UTC')::date = d),
+            (SELECT COUNT(*) FROM subscribers WHERE (created_at AT TIME ZONE 'UTC')::date = d AND first_sign_in_at IS NOT NULL),
+            (SELECT COUNT(*) FROM subscribers WHERE (unsubscribed_at AT TIME ZONE 'UTC')::date = d),
+            (SELECT COUNT(*) FROM deliveries WHERE date = d::date::text AND status = 'bounced')
+                + (SELECT COUNT(*) FROM digest_deliveries WHERE week_ending = d::date::text AND status = 'bounced')
+        FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS d
+        ORDER BY d`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []SignupDay
+	for rows.Next() {
+		var d SignupDay
+		if err := rows.Scan(&d.Date, &d.Signups, &d.SignedIn, &d.Unsubscribes, &d.Bounces); err != nil {
+			return nil, err
+		}
+		if d.Signups > 0 {
+			d.SignedInRate = float64(d.SignedIn) / float64(d.Signups)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// CountryCount is the number of active subscribers in one country.
+type CountryCount struct {
+	// Country is as entered at signup, trimmed and upper-cased; "" if
+	// none was given.
+	Country string `json:"country"`
+	Active  int    `json:"active"`
+}
+
+// ListActiveByCountry counts active subscribers per country, largest
+// first.
+func ListActiveByCountry(dbh *sql.DB) ([]CountryCount, error) {
+	rows, err := dbh.Query(`SELECT UPPER(TRIM(country)) AS c, COUNT(*) FROM subscribers
+        WHERE unsubscribed_at IS NULL GROUP BY c ORDER BY COUNT(*) DESC, c`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []CountryCount
+	for rows.Next() {
+		var c CountryCount
+		if err := rows.Scan(&c.Country, &c.Active); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// DeliveryRun is the outcome of one worker delivery run.
+type DeliveryRun struct {
+	ID          int       `json:"id"`
+	Kind        string    `json:"kind"` // "daily" or "digest"
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	Sent        int       `json:"sent"`
+	Failed      int       `json:"failed"`
+	Bounced     int       `json:"bounced"`
+	SuccessRate float64   `json:"success_rate"`
+}
+
+// RecordDeliveryRun stores the outcome of a delivery run.
+func RecordDeliveryRun(dbh *sql.DB, r DeliveryRun) error {
+	_, err := dbh.Exec(`INSERT INTO delivery_runs (kind, started_at, finished_at, sent, failed, bounced)
+        VALUES ($1,$2,$3,$4,$5,$6)`, r.Kind, r.StartedAt, r.FinishedAt, r.Sent, r.Failed, r.Bounced)
+	return err
+}
+
+// ListDeliveryRuns returns the runs started between from and to, newest
+// first.
+func ListDeliveryRuns(dbh *sql.DB, from, to time.Time) ([]DeliveryRun, error) {
+	rows, err := dbh.Query(`SELECT id, kind, started_at, finished_at, sent, failed, bounced FROM delivery_runs
+        WHERE started_at >= $1 AND started_at < $2 ORDER BY started_at DESC`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []DeliveryRun
+	for rows.Next() {
+		var r DeliveryRun
+		if err := rows.Scan(&r.ID, &r.Kind, &r.StartedAt, &r.FinishedAt, &r.Sent, &r.Failed, &r.Bounced); err != nil {
+			return nil, err
+		}
+		if n := r.Sent + r.Failed + r.Bounced; n > 0 {
+			r.SuccessRate = float64(r.Sent) / float64(n)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// PayloadRevision is one published version of a date's payload.
+type PayloadRevision struct {
+	Date          string          `json:"date"`
+	Revision      int             `json:"revision"`
+	SchemaVersion int             `json:"schema_version"`
+	Author        string          `json:"author"`
+	Reason        string          `json:"reason"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Payload       scripture.Daily `json:"payload"`
+}
+
+// ListPayloadRevisions returns every revision for date, newest first.
+func ListPayloadRevisions(dbh *sql.DB, date string) ([]PayloadRevision, error) {
+	rows, err := dbh.Query(`SELECT date, revision, schema_version, author, reason, created_at, payload_json
+        FROM daily_payload_revisions WHERE date=$1 ORDER BY revision DESC`, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []PayloadRevision
+	for rows.Next() {
+		r, err := scanPayloadRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *r)
+	}
+	return out, rows.Err()
+}
+
+func GetPayloadRevision(dbh *sql.DB, date string, revision int) (*PayloadRevision, error) {
+	row := dbh.QueryRow(`SELECT date, revision, schema_version, author, reason, created_at, payload_json
+        FROM daily_payload_revisions WHERE date=$1 AND revision=$2`, date, revision)
+	r, err := scanPayloadRevision(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errors.New("not_found")
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func scanPayloadRevision(row rowScanner) (*PayloadRevision, error) {
+	var r PayloadRevision
+	var js string
+	if err := row.Scan(&r.Date, &r.Revision, &r.SchemaVersion, &r.Author, &r.Reason, &r.CreatedAt, &js); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(js), &r.Payload); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/your/module/internal/calendar"
+)
+
+func ListCalendarRules(dbh *sql.DB) ([]calendar.Rule, error) {
+	rows, err := dbh.Query(`SELECT id, name, calendar, month, day, offset_days, duration_days, topic, priority
+        FROM calendar_rules ORDER BY priority DESC, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []calendar.Rule
+	for rows.Next() {
+		var r calendar.Rule
+		if err := rows.Scan(&r.ID, &r.Name, &r.Calendar, &r.Month, &r.Day, &r.Offset, &r.Duration, &r.Topic, &r.Priority); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func CreateCalendarRule(dbh *sql.DB, r calendar.Rule) (int, error) {
+	var id int
+	err := dbh.QueryRow(`INSERT INTO calendar_rules(name, calendar, month, day, offset_days, duration_days, topic, priority)
+        VALUES($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`,
+		r.Name, r.Calendar, r.Month, r.Day, r.Offset, r.Duration, r.Topic, r.Priority).Scan(&id)
+	return id, err
+}
+
+func DeleteCalendarRule(dbh *sql.DB, id int) error {
+	res, err := dbh.Exec(`DELETE FROM calendar_rules WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("not_found")
+	}
+	return nil
+}
+
+func SeedCalendarRules(db *sql.DB) {
+	// Only seed if table is empty
+	var count int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM calendar_rules`).Scan(&count)
+	if count > 0 {
+		return
+	}
+	db.Exec(`INSERT INTO calendar_rules (name, calendar, month, day, offset_days, duration_days, topic, priority) VALUES
+        -- Islamic (tabular Hijri)
+        ('Ramadan', 'hijri', 9, 1, 0, 30, 'patience', 10),
+        ('Eid al-Fitr', 'hijri', 10, 1, 0, 1, 'generosity', 20),
+        ('Eid al-Adha', 'hijri', 12, 10, 0, 1, 'generosity', 20),
+        -- Jewish (Hebrew)
+        ('Rosh Hashanah', 'hebrew', 7, 1, 0, 2, 'faith', 20),
+        ('Yom Kippur', 'hebrew', 7, 10, 0, 1, 'justice', 20),
+        ('Passover', 'hebrew', 1, 15, 0, 8, 'faith', 15),
+        ('Purim', 'hebrew', 13, 14, 0, 1, 'generosity', 20),
+        -- Christian (Gregorian liturgical)
+        ('Lent', 'easter', 0, 0, -46, 46, 'patience', 5),
+        ('Good Friday', 'easter', 0, 0, -2, 1, 'faith', 20),
+        ('Easter Sunday', 'easter', 0, 0, 0, 1, 'faith', 20),
+        ('Christmas Day', 'gregorian', 12, 25, 0, 1, 'generosity', 20),
+        -- Civic
+        ('Human Rights Day', 'gregorian', 12, 10, 0, 1, 'justice', 20)
+    `)
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateLoginToken stores the hash of a one-time magic-link token.
+func CreateLoginToken(dbh *sql.DB, subscriberID int, hash string, ttl time.Duration) error {
+	_, err := dbh.Exec(`INSERT INTO login_tokens (token_hash, subscriber_id, expires_at) VALUES ($1,$2,$3)`,
+		hash, subscriberID, time.Now().Add(ttl))
+	return err
+}
+
+// RecentLoginToken reports whether a login link was sent to the subscriber
+// within the last d, to throttle repeated requests.
+func RecentLoginToken(dbh *sql.DB, subscriberID int, d time.Duration) (bool, error) {
+	var n int
+	err := dbh.QueryRow(`SELECT COUNT(*) FROM login_tokens WHERE subscriber_id=$1 AND created_at > $2`,
+		subscriberID, time.Now().Add(-d)).Scan(&n)
+	return n > 0, err
+}
+
+// ConsumeLoginToken marks an unexpired, unused token as used and returns its
+// subscriber. It returns a "not_found" error for any other token, so a link
+// works exactly once. The first link used is recorded as the subscriber's
+// first sign-in.
+func ConsumeLoginToken(dbh *sql.DB, hash string) (int, error) {
+	var id int
+	err := dbh.QueryRow(`WITH t AS (UPDATE login_tokens SET used_at=CURRENT_TIMESTAMP
+            WHERE token_hash=$1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+            RETURNING subscriber_id)
+        UPDATE subscribers s SET first_sign_in_at = COALESCE(s.first_sign_in_at, CURRENT_TIMESTAMP)
+        FROM t WHERE s.id = t.subscriber_id
+        RETURNING s.id`, hash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errors.New("not_found")
+	}
+	return id, err
+}
+
+// CreateSession stores the hash of a session cookie value.
+func CreateSession(dbh *sql.DB, subscriberID int, hash string, ttl time.Duration) error {
+	_, err := dbh.Exec(`INSERT INTO sessions (token_hash, subscriber_id, expires_at) VALUES ($1,$2,$3)`,
+		hash, subscriberID, time.Now().Add(ttl))
+	return err
+}
+
+// SessionSubscriber returns the subscriber of an unexpired session, or a
+// "not_found" error.
+func SessionSubscriber(dbh *sql.DB, hash string) (int, error) {
+	var id int
+	err := dbh.QueryRow(`SELECT subscriber_id FROM sessions WHERE token_hash=$1 AND expires_at > CURRENT_TIMESTAMP`,
+		hash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, errors.New("not_found")
+	}
+	return id, err
+}
+
+func DeleteSession(dbh *sql.DB, hash string) error {
+	_, err := dbh.Exec(`DELETE FROM sessions WHERE token_hash=$1`, hash)
+	return err
+}
+
+// PurgeExpiredLogins removes expired sessions and login tokens.
+func PurgeExpiredLogins(dbh *sql.DB) error {
+	if _, err := dbh.Exec(`DELETE FROM sessions WHERE expires_at < CURRENT_TIMESTAMP`); err != nil {
+		return err
+	}
+	_, err := dbh.Exec(`DELETE FROM login_tokens WHERE expires_at < CURRENT_TIMESTAMP - INTERVAL '1 day'`)
+	return err
+}
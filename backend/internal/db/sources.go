@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// Source is an entry in the tradition registry. ID matches verses.source.
+type Source struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Position orders passages within a payload, lowest first.
+	Position int `json:"position"`
+	// CitationStyle formats a reference for display; "{ref}" is replaced
+	// with the verse reference, e.g. "Qur'an {ref}" gives "Qur'an 2:177".
+	CitationStyle string `json:"citation_style"`
+	Enabled       bool   `json:"enabled"`
+}
+
+// Cite formats ref using the source's citation style.
+func (s Source) Cite(ref string) string {
+	return scripture.Cite(s.CitationStyle, ref)
+}
+
+// ensureDefaultSources registers the traditions the site launched with,
+// leaving any edits to them alone.
+func ensureDefaultSources(db *sql.DB) {
+	for i, s := range scripture.LegacySources {
+		_, _ = db.Exec(`INSERT INTO sources (id, name, position, citation_style, enabled)
+            VALUES ($1,$2,$3,$4,TRUE) ON CONFLICT (id) DO NOTHING`,
+			s.ID, s.Name, (i+1)*10, s.CitationStyle)
+	}
+}
+
+// ListSources returns the registry in display order. With enabledOnly,
+// disabled sources are left out.
+func ListSources(dbh *sql.DB, enabledOnly bool) ([]Source, error) {
+	rows, err := dbh.Query(`SELECT id, name, position, citation_style, enabled FROM sources
+        WHERE enabled OR NOT $1 ORDER BY position, id`, enabledOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Source
+	for rows.Next() {
+		var s Source
+		if err := rows.Scan(&s.ID, &s.Name, &s.Position, &s.CitationStyle, &s.Enabled); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func UpsertSource(dbh *sql.DB, s Source) error {
+	_, err := dbh.Exec(`INSERT INTO sources (id, name, position, citation_style, enabled)
+        VALUES ($1,$2,$3,$4,$5)
+        ON CONFLICT (id) DO UPDATE SET name=excluded.name, position=excluded.position,
+            citation_style=excluded.citation_style, enabled=excluded.enabled`,
+		s.ID, s.Name, s.Position, s.CitationStyle, s.Enabled)
+	return err
+}
+
+// CountSourceVerses returns how many verses a source has.
+func CountSourceVerses(dbh *sql.DB, source string) (int, error) {
+	var n int
+	err := dbh.QueryRow(`SELECT COUNT(*) FROM verses WHERE source=$1`, source).Scan(&n)
+	return n, err
+}
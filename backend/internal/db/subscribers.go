@@ -0,0 +1,148 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Subscriber is someone who signed up through /api/subscribe/email.
+type Subscriber struct {
+	ID          int         `json:"id"`
+	FullName    string      `json:"full_name"`
+	Email       string      `json:"email"`
+	Phone       string      `json:"phone"`
+	Address     string      `json:"address"`
+	City        string      `json:"city"`
+	Country     string      `json:"country"`
+	Timezone    string      `json:"timezone"`
+	Preferences Preferences `json:"preferences"`
+	Billing     Billing     `json:"billing"`
+	CreatedAt   time.Time   `json:"created_at"`
+	// FirstSignInAt is when the subscriber first used a sign-in link.
+	FirstSignInAt  *time.Time `json:"first_sign_in_at,omitempty"`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at,omitempty"`
+}
+
+// UpsertSubscriber stores a signup keyed by email and reports whether it
+// created a new subscriber. Signing up again with a known address changes
+// nothing: the profile, preferences and unsubscribed state of an existing
+// subscriber are only changed by the subscriber after signing in (see
+// UpdateSubscriberProfile, UpdatePreferences and SetSubscribed).
+func UpsertSubscriber(dbh *sql.DB, s Subscriber) (int, bool, error) {
+	var id int
+	var created bool
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	p := s.Preferences.withDefaults()
+	err := dbh.QueryRow(`INSERT INTO subscribers(full_name, email, phone, address, city, country, timezone,
+            sources, frequency, digest_weekday, language, delivery_hour, no_tracking)
+        VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+        ON CONFLICT(email) DO UPDATE SET email=subscribers.email
+        RETURNING id, (xmax = 0)`,
+		s.FullName, s.Email, s.Phone, s.Address, s.City, s.Country, s.Timezone,
+		strings.Join(p.Sources, ","), p.Frequency, p.DigestWeekday, p.Language, p.DeliveryHour, p.NoTracking).Scan(&id, &created)
+	return id, created, err
+}
+
+const subscriberColumns = `id, full_name, email, phone, address, city, country, timezone,
+        sources, frequency, digest_weekday, language, delivery_hour, no_tracking,
+        billing_status, billing_period_end, past_due_since, comped, comp_until, created_at, first_sign_in_at, unsubscribed_at`
+
+func scanSubscriber(row rowScanner) (*Subscriber, error) {
+	var s Subscriber
+	var sources string
+	p, b := &s.Preferences, &s.Billing
+	if err := row.Scan(&s.ID, &s.FullName, &s.Email, &s.Phone, &s.Address, &s.City, &s.Country, &s.Timezone,
+		&sources, &p.Frequency, &p.DigestWeekday, &p.Language, &p.DeliveryHour, &p.NoTracking,
+		&b.Status, &b.PeriodEnd, &b.PastDueSince, &b.Comped, &b.CompUntil, &s.CreatedAt, &s.FirstSignInAt, &s.UnsubscribedAt); err != nil {
+		return nil, err
+	}
+	p.Sources = splitList(sources)
+	if p.Sources == nil {
+		p.Sources = []string{}
+	}
+	return &s, nil
+}
+
+func ListActiveSubscribers(dbh *sql.DB) ([]Subscriber, error) {
+	rows, err := dbh.Query(`SELECT ` + subscriberColumns + ` FROM subscribers WHERE unsubscribed_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Subscriber
+	for rows.Next() {
+		s, err := scanSubscriber(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// GetSubscriber returns a subscriber by ID, or a "not_found" error.
+func GetSubscriber(dbh *sql.DB, id int) (*Subscriber, error) {
+	return oneSubscriber(dbh.QueryRow(`SELECT `+subscriberColumns+` FROM subscribers WHERE id=$1`, id))
+}
+
+// GetSubscriberByEmail returns a subscriber by lower-cased email, or a
+// "not_found" error.
+func GetSubscriberByEmail(dbh *sql.DB, email string) (*Subscriber, error) {
+	return oneSubscriber(dbh.QueryRow(`SELECT `+subscriberColumns+` FROM subscribers WHERE email=$1`, email))
+}
+
+func oneSubscriber(row *sql.Row) (*Subscriber, error) {
+	s, err := scanSubscriber(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("not_found")
+	}
+	return s, err
+}
+
+// UpdateSubscriberProfile changes the contact details a subscriber can edit
+// themselves. Email is the login identity and is not changed here.
+func UpdateSubscriberProfile(dbh *sql.DB, s Subscriber) error {
+	_, err := dbh.Exec(`UPDATE subscribers SET full_name=$2, phone=$3, address=$4, city=$5, country=$6, timezone=$7
+        WHERE id=$1`, s.ID, s.FullName, s.Phone, s.Address, s.City, s.Country, s.Timezone)
+	return err
+}
+
+// SetSubscribed pauses or resumes a subscriber's emails.
+func SetSubscribed(dbh *sql.DB, id int, subscribed bool) error {
+	_, err := dbh.Exec(`UPDATE subscribers SET unsubscribed_at = CASE WHEN $2 THEN NULL
+            ELSE COALESCE(unsubscribed_at, CURRENT_TIMESTAMP) END
+        WHERE id=$1`, id, subscribed)
+	return err
+}
+
+// DeleteSubscriber removes a subscriber along with their deliveries,
+// sessions and login tokens.
+func DeleteSubscriber(dbh *sql.DB, id int) error {
+	_, err := dbh.Exec(`DELETE FROM subscribers WHERE id=$1`, id)
+	return err
+}
+
+// ClaimDelivery records that the daily email for date is about to be sent to
+// a subscriber. It returns false if a delivery was already claimed, so every
+// subscriber gets at most one email per date however often delivery runs.
+func ClaimDelivery(dbh *sql.DB, date string, subscriberID int) (bool, error) {
+	res, err := dbh.Exec(`INSERT INTO deliveries(date, subscriber_id, status) VALUES($1,$2,'pending')
+        ON CONFLICT(date, subscriber_id) DO NOTHING`, date, subscriberID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// FinishDelivery records the outcome ('sent', 'failed' or 'bounced') of a
+// claimed delivery.
+func FinishDelivery(dbh *sql.DB, date string, subscriberID int, status, errMsg string) error {
+	_, err := dbh.Exec(`UPDATE deliveries SET status=$3, error=$4, finished_at=CURRENT_TIMESTAMP
+        WHERE date=$1 AND subscriber_id=$2`, date, subscriberID, status, errMsg)
+	return err
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/your/module/internal/summary"
+)
+
+func ListSummaryTemplates(dbh *sql.DB) ([]summary.Template, error) {
+	rows, err := dbh.Query(`SELECT id, topic, body, enabled FROM summary_templates ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []summary.Template
+	for rows.Next() {
+		var t summary.Template
+		if err := rows.Scan(&t.ID, &t.Topic, &t.Body, &t.Enabled); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+func CreateSummaryTemplate(dbh *sql.DB, t summary.Template) (int, error) {
+	var id int
+	err := dbh.QueryRow(`INSERT INTO summary_templates (topic, body, enabled) VALUES ($1,$2,$3) RETURNING id`,
+		t.Topic, t.Body, t.Enabled).Scan(&id)
+	return id, err
+}
+
+func DeleteSummaryTemplate(dbh *sql.DB, id int) error {
+	return deleteByID(dbh, `DELETE FROM summary_templates WHERE id=$1`, id)
+}
+
+// ListTopicBlurbs returns the blurbs for topic in insertion order, or every
+// blurb when topic is empty.
+func ListTopicBlurbs(dbh *sql.DB, topic string) ([]summary.Blurb, error) {
+	rows, err := dbh.Query(`SELECT id, topic, body FROM topic_blurbs WHERE $1 = '' OR topic = $1 ORDER BY topic, id`, topic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []summary.Blurb
+	for rows.Next() {
+		var b summary.Blurb
+		if err := rows.Scan(&b.ID, &b.Topic, &b.Body); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func CreateTopicBlurb(dbh *sql.DB, b summary.Blurb) (int, error) {
+	var id int
+	err := dbh.QueryRow(`INSERT INTO topic_blurbs (topic, body) VALUES ($1,$2) RETURNING id`, b.Topic, b.Body).Scan(&id)
+	return id, err
+}
+
+func DeleteTopicBlurb(dbh *sql.DB, id int) error {
+	return deleteByID(dbh, `DELETE FROM topic_blurbs WHERE id=$1`, id)
+}
+
+// CountTopicOccurrences returns how many payloads before date featured
+// topic. The worker uses it to rotate summary variants.
+func CountTopicOccurrences(dbh *sql.DB, topic, before string) (int, error) {
+	var n int
+	err := dbh.QueryRow(`SELECT COUNT(*) FROM daily_payloads WHERE payload_json->>'area' = $1 AND date < $2`,
+		topic, before).Scan(&n)
+	return n, err
+}
+
+// SummaryCache stores provider-generated summaries in generated_summaries.
+// It implements summary.Cache.
+type SummaryCache struct {
+	DB *sql.DB
+	// ReadOnly serves stored summaries but does not store new ones, for
+	// dry runs.
+	ReadOnly bool
+}
+
+func (c SummaryCache) GetSummary(date, key string) (string, bool, error) {
+	var text string
+	err := c.DB.QueryRow(`SELECT text FROM generated_summaries WHERE date=$1 AND input_hash=$2`, date, key).Scan(&text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	return text, err == nil, err
+}
+
+func (c SummaryCache) PutSummary(date, key, provider, text string) error {
+	if c.ReadOnly {
+		return nil
+	}
+	_, err := c.DB.Exec(`INSERT INTO generated_summaries (date, input_hash, provider, text) VALUES ($1,$2,$3,$4)
+        ON CONFLICT (date, input_hash) DO UPDATE SET provider=EXCLUDED.provider, text=EXCLUDED.text, created_at=CURRENT_TIMESTAMP`,
+		date, key, provider, text)
+	return err
+}
+
+func deleteByID(dbh *sql.DB, query string, id int) error {
+	res, err := dbh.Exec(query, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("not_found")
+	}
+	return nil
+}
+
+const sourcesTemplate = `{{title (number (len .Passages))}} traditions, one theme: {{.Topic}}. {{if .Blurb}}{{.Blurb}} {{end}}Today's passages are {{refs .Passages}}.`
+
+func SeedSummaryContent(db *sql.DB) {
+	// Only seed if tables are empty
+	var count int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM summary_templates`).Scan(&count)
+	if count == 0 {
+		db.Exec(`INSERT INTO summary_templates (topic, body, enabled) VALUES
+            ('', $1, TRUE),
+            ('', '{{if .Blurb}}{{.Blurb}} {{end}}Read side by side, {{citations .Passages}} each speak to {{.Topic}} in their own voice.', TRUE),
+            ('', '{{title .Topic}} is today''s common thread.{{with passage "bible"}} "{{truncate 90 .Text}}" ({{.Citation}}){{end}} {{if .Blurb}}{{.Blurb}}{{else}}Each tradition approaches it differently: {{refs .Passages}}.{{end}}', TRUE),
+            ('', $2, TRUE)
+        `, summary.Default, sourcesTemplate)
+	}
+	// The first seed hardcoded the number of traditions.
+	_, _ = db.Exec(`UPDATE summary_templates SET body=$1 WHERE body=$2`, sourcesTemplate,
+		`Four traditions, one theme: {{.Topic}}. {{if .Blurb}}{{.Blurb}} {{end}}Today's passages are {{refs .Passages}}.`)
+	_ = db.QueryRow(`SELECT COUNT(*) FROM topic_blurbs`).Scan(&count)
+	if count == 0 {
+		db.Exec(`INSERT INTO topic_blurbs (topic, body) VALUES
+            ('generosity', 'Every tradition here treats giving as a discipline of the heart, not only of the purse.'),
+            ('generosity', 'Generosity is framed less as charity than as justice owed to those in need.'),
+            ('patience', 'Patience here is not passivity but steadiness: trusting the right time while staying present.'),
+            ('patience', 'Each voice asks for endurance that ripens into maturity rather than mere waiting.'),
+            ('faith', 'Faith appears as confidence in what is unseen, and as trust that one is not burdened beyond one''s strength.'),
+            ('faith', 'These passages tie faith to the present moment: belief that acts now rather than later.'),
+            ('justice', 'Justice is asked of us even when it costs us, and without favour to rich or poor.'),
+            ('justice', 'Each tradition couples justice with humility and the work of setting things right.')
+        `)
+	}
+}
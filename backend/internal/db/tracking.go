@@ -0,0 +1,140 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// EmailMessage is a tracked daily email: its opens and clicks are recorded
+// under Token. It is stored as "sending" before it goes out, then marked
+// "sent", "failed" or "bounced".
+type EmailMessage struct {
+	Token        string
+	Date         string
+	SubscriberID int
+	Topic        string
+	// Links are the original URLs of the email's tracked links, by n.
+	Links []string
+}
+
+// CreateEmailMessage stores a tracked email about to be sent, and its
+// links.
+func CreateEmailMessage(dbh *sql.DB, m EmailMessage) error {
+	tx, err := dbh.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO email_messages (token, date, subscriber_id, topic, status) VALUES ($1,$2,$3,$4,'sending')`,
+		m.Token, m.Date, m.SubscriberID, m.Topic); err != nil {
+		return err
+	}
+	for n, url := range m.Links {
+		if _, err := tx.Exec(`INSERT INTO email_links (token, n, url) VALUES ($1,$2,$3)`, m.Token, n, url); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// FinishEmailMessage records whether a tracked email went out: its
+// delivery status, "sent" or a failure.
+func FinishEmailMessage(dbh *sql.DB, token, status string) error {
+	_, err := dbh.Exec(`UPDATE email_messages SET status=$2 WHERE token=$1`, token, status)
+	return err
+}
+
+// RecordEmailOpen records that the email with token was opened, unless its
+// subscriber has opted out of tracking. Unknown tokens are ignored.
+func RecordEmailOpen(dbh *sql.DB, token string) error {
+	_, err := dbh.Exec(`INSERT INTO email_events (token, kind)
+        SELECT m.token, 'open' FROM email_messages m JOIN subscribers s ON s.id = m.subscriber_id
+        WHERE m.token = $1 AND NOT s.no_tracking`, token)
+	return err
+}
+
+// GetEmailLink returns the URL of link n of the email with token.
+func GetEmailLink(dbh *sql.DB, token string, n int) (string, error) {
+	var url string
+	err := dbh.QueryRow(`SELECT url FROM email_links WHERE token=$1 AND n=$2`, token, n).Scan(&url)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errors.New("not_found")
+	}
+	return url, err
+}
+
+// RecordEmailClick returns the URL of link n of the email with token and,
+// unless its subscriber has opted out of tracking, records the click.
+func RecordEmailClick(dbh *sql.DB, token string, n int) (string, error) {
+	url, err := GetEmailLink(dbh, token, n)
+	if err != nil {
+		return "", err
+	}
+	_, err = dbh.Exec(`INSERT INTO email_events (token, kind, link)
+        SELECT m.token, 'click', $2 FROM email_messages m JOIN subscribers s ON s.id = m.subscriber_id
+        WHERE m.token = $1 AND NOT s.no_tracking`, token, n)
+	return url, err
+}
+
+// DeleteEmailEvents deletes the recorded opens and clicks of a subscriber's
+// emails.
+func DeleteEmailEvents(dbh *sql.DB, subscriberID int) error {
+	_, err := dbh.Exec(`DELETE FROM email_events WHERE token IN
+        (SELECT token FROM email_messages WHERE subscriber_id=$1)`, subscriberID)
+	return err
+}
+
+// Engagement is how the tracked daily emails of one date, or of one topic,
+// were received.
+type Engagement struct {
+	Date  string `json:"date,omitempty"`
+	Topic string `json:"topic"`
+	// Sent counts tracked emails that went out; untracked ones and failed
+	// sends are left out.
+	Sent int `json:"sent"`
+	// Opened and Clicked count emails opened or clicked at least once. A
+	// click counts as an open, since images may be blocked.
+	Opened  int `json:"opened"`
+	Clicked int `json:"clicked"`
+	// Opens and Clicks count every event.
+	Opens     int     `json:"opens"`
+	Clicks    int     `json:"clicks"`
+	OpenRate  float64 `json:"open_rate"`
+	ClickRate float64 `json:"click_rate"`
+}
+
+// ListEngagement reports on the tracked emails for dates from to to
+// (inclusive), per date, or per topic if byTopic is set.
+func ListEngagement(dbh *sql.DB, from, to string, byTopic bool) ([]Engagement, error) {
+	date, group, order := "m.date", "m.date, m.topic", "m.date, m.topic"
+	if byTopic {
+		date, group, order = "''", "m.topic", "sent DESC, m.topic"
+	}
+	rows, err := dbh.Query(`SELECT `+date+`, m.topic, COUNT(*) AS sent,
+            COUNT(*) FILTER (WHERE e.opens > 0 OR e.clicks > 0),
+            COUNT(*) FILTER (WHERE e.clicks > 0),
+            COALESCE(SUM(e.opens), 0), COALESCE(SUM(e.clicks), 0)
+        FROM email_messages m
+        LEFT JOIN (SELECT token, COUNT(*) FILTER (WHERE kind = 'open') AS opens,
+                COUNT(*) FILTER (WHERE kind = 'click') AS clicks
+            FROM email_events GROUP BY token) e ON e.token = m.token
+        WHERE m.date >= $1 AND m.date <= $2 AND m.status = 'sent'
+        GROUP BY `+group+` ORDER BY `+order, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Engagement
+	for rows.Next() {
+		var e Engagement
+		if err := rows.Scan(&e.Date, &e.Topic, &e.Sent, &e.Opened, &e.Clicked, &e.Opens, &e.Clicks); err != nil {
+			return nil, err
+		}
+		if e.Sent > 0 {
+			e.OpenRate = float64(e.Opened) / float64(e.Sent)
+			e.ClickRate = float64(e.Clicked) / float64(e.Sent)
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,364 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// VerseFilter narrows ListVerses. Zero values match everything.
+type VerseFilter struct {
+	Source   string
+	Topic    string
+	Query    string // case-insensitive match on ref or text
+	Untagged bool   // only verses without topics
+	Limit    int
+	Offset   int
+}
+
+// ListVerses returns the verses matching f ordered by source and ID.
+func ListVerses(dbh *sql.DB, f VerseFilter) ([]scripture.Verse, error) {
+	q := `SELECT id, source, ref, text, topics FROM verses
+        WHERE ($1 = '' OR source = $1)
+          AND ($2 = '' OR ` + hasTopic("$2") + `)
+          AND ($3 = '' OR ref ILIKE '%' || $3 || '%' OR text ILIKE '%' || $3 || '%')
+          AND (NOT $4 OR TRIM(topics) = '')
+        ORDER BY source, id`
+	args := []any{f.Source, f.Topic, f.Query, f.Untagged}
+	if f.Limit > 0 {
+		q += ` LIMIT $5 OFFSET $6`
+		args = append(args, f.Limit, f.Offset)
+	}
+	rows, err := dbh.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []scripture.Verse
+	for rows.Next() {
+		v, err := scanVerse(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *v)
+	}
+	return out, rows.Err()
+}
+
+func scanVerse(row rowScanner) (*scripture.Verse, error) {
+	var v scripture.Verse
+	if err := row.Scan(&v.ID, &v.Source, &v.Ref, &v.Text, &v.Topics); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// FindVerseByRef returns another verse from the same source whose reference
+// matches ref ignoring case and spacing, or a "not_found" error. The verse
+// with ID exclude is ignored so an update does not clash with itself.
+func FindVerseByRef(dbh *sql.DB, source, ref string, exclude int) (*scripture.Verse, error) {
+	v, err := scanVerse(dbh.QueryRow(`SELECT id, source, ref, text, topics FROM verses
+        WHERE source = $1 AND LOWER(REGEXP_REPLACE(ref, '\s+', '', 'g')) = $2 AND id <> $3
+        ORDER BY id LIMIT 1`, source, NormalizeRef(ref), exclude))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, errors.New("not_found")
+	}
+	return v, err
+}
+
+// NormalizeRef is the form references are compared in to detect duplicates.
+func NormalizeRef(ref string) string {
+	return strings.ToLower(strings.Join(strings.Fields(ref), ""))
+}
+
+// CreateVerse inserts v and records it in the audit log as actor.
+func CreateVerse(dbh *sql.DB, v scripture.Verse, actor string) (int, error) {
+	tx, err := dbh.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if err := tx.QueryRow(`INSERT INTO verses (source, ref, text, topics) VALUES ($1,$2,$3,$4) RETURNING id`,
+		v.Source, v.Ref, v.Text, v.Topics).Scan(&v.ID); err != nil {
+		return 0, err
+	}
+	if err := writeAudit(tx, actor, "create", "verse", strconv.Itoa(v.ID), nil, v); err != nil {
+		return 0, err
+	}
+	return v.ID, tx.Commit()
+}
+
+// UpdateVerse replaces the verse with v.ID, returning a "not_found" error if
+// it does not exist.
+func UpdateVerse(dbh *sql.DB, v scripture.Verse, actor string) error {
+	return changeVerse(dbh, v.ID, actor, "update", func(before scripture.Verse) (*scripture.Verse, error) {
+		return &v, nil
+	})
+}
+
+// DeleteVerse removes a verse. Schedule overrides that pin it report it as
+// missing until they are edited.
+func DeleteVerse(dbh *sql.DB, id int, actor string) error {
+	return changeVerse(dbh, id, actor, "delete", func(scripture.Verse) (*scripture.Verse, error) {
+		return nil, nil
+	})
+}
+
+// TagVerses adds and removes topics on each verse in ids. Unknown IDs are
+// skipped. It returns the IDs of the verses that changed.
+func TagVerses(dbh *sql.DB, ids []int, add, remove []string, actor string) ([]int, error) {
+	var changed []int
+	for _, id := range ids {
+		var did bool
+		err := changeVerse(dbh, id, actor, "tag", func(before scripture.Verse) (*scripture.Verse, error) {
+			after := before
+			after.Topics = JoinTopics(editTopics(before.TopicList(), add, remove))
+			did = after.Topics != before.Topics
+			if !did {
+				return &before, nil
+			}
+			return &after, nil
+		})
+		if err != nil && err.Error() != "not_found" {
+			return changed, err
+		}
+		if did {
+			changed = append(changed, id)
+		}
+	}
+	return changed, nil
+}
+
+// changeVerse locks the verse, lets edit compute its new state (nil deletes
+// it) and writes the change and its audit entry in one transaction. Nothing
+// is written or audited if edit returns the verse unchanged.
+func changeVerse(dbh *sql.DB, id int, actor, action string, edit func(scripture.Verse) (*scripture.Verse, error)) error {
+	tx, err := dbh.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	before, err := scanVerse(tx.QueryRow(`SELECT id, source, ref, text, topics FROM verses WHERE id=$1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("not_found")
+		}
+		return err
+	}
+	after, err := edit(*before)
+	if err != nil {
+		return err
+	}
+	switch {
+	case after == nil:
+		if _, err := tx.Exec(`DELETE FROM verses WHERE id=$1`, id); err != nil {
+			return err
+		}
+	case *after == *before:
+		return nil
+	default:
+		after.ID = id
+		if _, err := tx.Exec(`UPDATE verses SET source=$2, ref=$3, text=$4, topics=$5 WHERE id=$1`,
+			id, after.Source, after.Ref, after.Text, after.Topics); err != nil {
+			return err
+		}
+	}
+	var logged any
+	if after != nil {
+		logged = after
+	}
+	if err := writeAudit(tx, actor, action, "verse", strconv.Itoa(id), before, logged); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TopicStats describes one topic and how many verses carry it per source.
+type TopicStats struct {
+	Topic   string         `json:"topic"`
+	Verses  int            `json:"verses"`
+	Sources map[string]int `json:"sources"`
+}
+
+// ListTopics returns every topic in use, alphabetically.
+func ListTopics(dbh *sql.DB) ([]TopicStats, error) {
+	verses, err := ListVerses(dbh, VerseFilter{})
+	if err != nil {
+		return nil, err
+	}
+	byTopic := map[string]*TopicStats{}
+	for _, v := range verses {
+		for _, t := range v.TopicList() {
+			s := byTopic[t]
+			if s == nil {
+				s = &TopicStats{Topic: t, Sources: map[string]int{}}
+				byTopic[t] = s
+			}
+			s.Verses++
+			s.Sources[v.Source]++
+		}
+	}
+	out := make([]TopicStats, 0, len(byTopic))
+	for _, s := range byTopic {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Topic < out[j].Topic })
+	return out, nil
+}
+
+// RenameTopic renames a topic on every verse, and in the schedule
+// overrides, calendar rules, summary templates and blurbs that refer to it.
+// It returns the number of verses changed, or a "not_found" error if no
+// verse carries the topic.
+func RenameTopic(dbh *sql.DB, from, to, actor string) (int, error) {
+	return rewriteTopic(dbh, from, to, actor)
+}
+
+// RemoveTopic removes a topic from every verse; the verses are kept. It
+// fails with a *TopicInUse error while a calendar rule or a schedule
+// override from yesterday on selects the topic. Summary templates and
+// blurbs for the topic are kept in case it is used again.
+func RemoveTopic(dbh *sql.DB, name, actor string) (int, error) {
+	return rewriteTopic(dbh, name, "", actor)
+}
+
+// TopicInUse lists what still selects a topic that was to be removed.
+type TopicInUse struct {
+	Overrides []string `json:"overrides"` // dates
+	Rules     []int    `json:"rules"`     // calendar rule IDs
+}
+
+func (e *TopicInUse) Error() string { return "topic_in_use" }
+
+// topicInUse returns the upcoming overrides and the rules that select
+// topic, or nil if there are none.
+func topicInUse(tx *sql.Tx, topic string) (*TopicInUse, error) {
+	in := &TopicInUse{Overrides: []string{}, Rules: []int{}}
+	rows, err := tx.Query(`SELECT date FROM schedule_overrides
+        WHERE topic=$1 AND date >= to_char(CURRENT_DATE - 1, 'YYYY-MM-DD') ORDER BY date`, topic)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		in.Overrides = append(in.Overrides, date)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows, err = tx.Query(`SELECT id FROM calendar_rules WHERE topic=$1 ORDER BY id`, topic)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		in.Rules = append(in.Rules, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(in.Overrides) == 0 && len(in.Rules) == 0 {
+		return nil, nil
+	}
+	return in, nil
+}
+
+func rewriteTopic(dbh *sql.DB, from, to, actor string) (int, error) {
+	tx, err := dbh.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(`SELECT id, source, ref, text, topics FROM verses
+        WHERE `+hasTopic("$1")+` FOR UPDATE`, from)
+	if err != nil {
+		return 0, err
+	}
+	var verses []scripture.Verse
+	for rows.Next() {
+		v, err := scanVerse(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		verses = append(verses, *v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(verses) == 0 {
+		return 0, errors.New("not_found")
+	}
+	if to == "" {
+		if in, err := topicInUse(tx, from); err != nil {
+			return 0, err
+		} else if in != nil {
+			return 0, in
+		}
+	}
+
+	var add []string
+	if to != "" {
+		add = []string{to}
+	}
+	var ids []int
+	for _, v := range verses {
+		topics := JoinTopics(editTopics(v.TopicList(), add, []string{from}))
+		if _, err := tx.Exec(`UPDATE verses SET topics=$2 WHERE id=$1`, v.ID, topics); err != nil {
+			return 0, err
+		}
+		ids = append(ids, v.ID)
+	}
+
+	action, after := "remove_topic", any(nil)
+	if to != "" {
+		action, after = "rename_topic", map[string]any{"topic": to}
+		for _, table := range []string{"schedule_overrides", "calendar_rules", "summary_templates", "topic_blurbs"} {
+			if _, err := tx.Exec(`UPDATE `+table+` SET topic=$2 WHERE topic=$1`, from, to); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := writeAudit(tx, actor, action, "topic", from, map[string]any{"topic": from, "verse_ids": ids}, after); err != nil {
+		return 0, err
+	}
+	return len(ids), tx.Commit()
+}
+
+// editTopics returns topics with remove taken out and add appended, keeping
+// the existing order and dropping duplicates.
+func editTopics(topics, add, remove []string) []string {
+	drop := map[string]bool{}
+	for _, t := range remove {
+		drop[t] = true
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range append(append([]string{}, topics...), add...) {
+		if drop[t] || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// JoinTopics stores a topic list in the comma-separated verses.topics form.
+func JoinTopics(topics []string) string {
+	return strings.Join(topics, ",")
+}
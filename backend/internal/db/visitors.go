@@ -0,0 +1,117 @@
+package db
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+)
+
+// VisitorDay is one endpoint's traffic on one UTC day.
+type VisitorDay struct {
+	Date     string `json:"date"`
+	Endpoint string `json:"endpoint"`
+	Hits     int    `json:"hits"`
+	// Uniques is the number of distinct visitors that day.
+	Uniques int `json:"unique_visitors"`
+}
+
+// RecordVisits adds hits to an endpoint's count for day and records the
+// visitor hashes seen, which may repeat. The hashes are dropped if the day
+// was compacted meanwhile, since its unique count is final.
+func RecordVisits(dbh *sql.DB, day, endpoint string, hits int, visitors []string) error {
+	tx, err := dbh.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if len(visitors) > 0 {
+		// The share lock holds off CompactVisitors until this commits.
+		var salt string
+		err := tx.QueryRow(`SELECT salt FROM visitor_salts WHERE day=$1 FOR SHARE`, day).Scan(&salt)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && salt == "") {
+			visitors = nil
+		} else if err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO visitor_daily (day, endpoint, hits) VALUES ($1,$2,$3)
+        ON CONFLICT (day, endpoint) DO UPDATE SET hits = visitor_daily.hits + excluded.hits`, day, endpoint, hits); err != nil {
+		return err
+	}
+	for _, v := range visitors {
+		if _, err := tx.Exec(`INSERT INTO visitor_uniques (day, endpoint, visitor) VALUES ($1,$2,$3)
+            ON CONFLICT DO NOTHING`, day, endpoint, v); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// VisitorSalt returns the salt for day, creating it on first use. Every
+// API replica gets the same salt, so a visitor is counted once per day.
+// It returns "" for a day that was already compacted, possibly by another
+// replica: a new salt would count its visitors a second time.
+func VisitorSalt(dbh *sql.DB, day string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if _, err := dbh.Exec(`INSERT INTO visitor_salts (day, salt)
+        SELECT $1, $2 WHERE NOT EXISTS (SELECT 1 FROM visitor_salts WHERE day >= $1 AND salt = '')
+        ON CONFLICT (day) DO NOTHING`, day, hex.EncodeToString(b)); err != nil {
+		return "", err
+	}
+	var salt string
+	err := dbh.QueryRow(`SELECT salt FROM visitor_salts WHERE day=$1`, day).Scan(&salt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return salt, err
+}
+
+// CompactVisitors turns the visitor hashes of days before day into counts
+// and deletes them. Those days' salts are blanked rather than deleted, so
+// no hash outlives its day and VisitorSalt does not create new ones.
+func CompactVisitors(dbh *sql.DB, day string) error {
+	tx, err := dbh.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Blanking the salts first waits for RecordVisits transactions still
+	// adding hashes for those days.
+	if _, err := tx.Exec(`UPDATE visitor_salts SET salt = '' WHERE day < $1 AND salt <> ''`, day); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE visitor_daily d SET uniques = COALESCE(d.uniques, 0) + u.n
+        FROM (SELECT day, endpoint, COUNT(*) AS n FROM visitor_uniques WHERE day < $1 GROUP BY day, endpoint) u
+        WHERE d.day = u.day AND d.endpoint = u.endpoint`, day); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM visitor_uniques WHERE day < $1`, day); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListVisitorDays returns the traffic of each endpoint from from to to
+// (inclusive), oldest first.
+func ListVisitorDays(dbh *sql.DB, from, to string) ([]VisitorDay, error) {
+	rows, err := dbh.Query(`SELECT d.day, d.endpoint, d.hits,
+            COALESCE(d.uniques, 0) + (SELECT COUNT(*) FROM visitor_uniques u WHERE u.day = d.day AND u.endpoint = d.endpoint)
+        FROM visitor_daily d WHERE d.day >= $1 AND d.day <= $2 ORDER BY d.day, d.endpoint`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []VisitorDay
+	for rows.Next() {
+		var v VisitorDay
+		if err := rows.Scan(&v.Date, &v.Endpoint, &v.Hits, &v.Uniques); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
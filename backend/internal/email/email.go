@@ -1,10 +1,20 @@
 package email
 
 import (
+	"bytes"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
 	"net/smtp"
+	"net/textproto"
 	"os"
 	"strings"
+	"time"
+
+	"github.com/your/module/internal/scripture"
 )
 
 // Config holds email configuration
@@ -15,6 +25,9 @@ type Config struct {
 	SMTPPassword string
 	FromEmail    string
 	FromName     string
+	// SiteURL is the public web app that links in emails point to. It is
+	// APP_URL, as for feeds and sign-in redirects.
+	SiteURL string
 }
 
 // LoadConfig loads email configuration from environment variables
@@ -26,6 +39,7 @@ func LoadConfig() *Config {
 		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
 		FromEmail:    getEnv("FROM_EMAIL", getEnv("SMTP_USER", "noreply@scripturedaily.com")),
 		FromName:     getEnv("FROM_NAME", "Scripture Daily"),
+		SiteURL:      strings.TrimRight(getEnv("APP_URL", "http://localhost:5173"), "/"),
 	}
 }
 
@@ -38,6 +52,40 @@ func getEnv(key, defaultValue string) string {
 
 // SendEmail sends an email using SMTP
 func SendEmail(cfg *Config, to, subject, body string) error {
+	return send(cfg, to, subject, "Content-Type: text/plain; charset=\"UTF-8\"\r\n\r\n"+body+"\r\n")
+}
+
+// SendEmailHTML sends an email with a plain text and an HTML version of
+// the body; mail clients show the HTML one if they can.
+func SendEmailHTML(cfg *Config, to, subject, text, html string) error {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, part := range []struct{ typ, body string }{{"text/plain", text}, {"text/html", html}} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.typ + `; charset="UTF-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return err
+		}
+		if err := qp.Close(); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return err
+	}
+	return send(cfg, to, subject,
+		"Content-Type: multipart/alternative; boundary=\""+mw.Boundary()+"\"\r\n\r\n"+buf.String())
+}
+
+// send adds the addressing headers to content, which starts with the
+// Content-Type header, and sends it.
+func send(cfg *Config, to, subject, content string) error {
 	// Check if SMTP is configured
 	if cfg.SMTPUser == "" || cfg.SMTPPassword == "" {
 		return fmt.Errorf("SMTP not configured - set SMTP_USER and SMTP_PASSWORD environment variables")
@@ -50,16 +98,14 @@ func SendEmail(cfg *Config, to, subject, body string) error {
 			"To: " + to + "\r\n" +
 			"Subject: " + subject + "\r\n" +
 			"MIME-Version: 1.0\r\n" +
-			"Content-Type: text/plain; charset=\"UTF-8\"\r\n" +
-			"\r\n" +
-			body + "\r\n")
+			content)
 
 	// Set up authentication
 	auth := smtp.PlainAuth("", cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPHost)
 
 	// Send email
 	addr := cfg.SMTPHost + ":" + cfg.SMTPPort
-	err := smtp.SendMail(addr, auth, cfg.FromEmail, []string{to}, msg)
+	err := sendMail(addr, auth, cfg.FromEmail, to, msg)
 	if err != nil {
 		return fmt.Errorf("failed to send email: %w", err)
 	}
@@ -67,6 +113,61 @@ func SendEmail(cfg *Config, to, subject, body string) error {
 	return nil
 }
 
+// RecipientError is the mail server's reply rejecting the recipient, to
+// the RCPT command. Failures at other stages, such as authentication or
+// the sender address, are not about the recipient and are returned as is.
+type RecipientError struct {
+	Err error
+}
+
+func (e *RecipientError) Error() string { return "recipient rejected: " + e.Err.Error() }
+
+func (e *RecipientError) Unwrap() error { return e.Err }
+
+// sendMail is smtp.SendMail for one recipient, except that a rejected
+// recipient is reported as a *RecipientError.
+func sendMail(addr string, a smtp.Auth, from, to string, msg []byte) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	c, err := smtp.Dial(addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if a != nil {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("smtp: server doesn't support AUTH")
+		}
+		if err := c.Auth(a); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return &RecipientError{Err: err}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
 // SendWelcomeEmail sends a welcome email to new subscribers
 func SendWelcomeEmail(cfg *Config, name, email string) error {
 	subject := "Welcome to Scripture Daily!"
@@ -101,68 +202,243 @@ To unsubscribe, please contact support@net1io.com
 	return SendEmail(cfg, email, subject, body)
 }
 
-// Daily represents the daily scripture payload
-type Daily struct {
-	Date    string            `json:"date"`
-	Area    string            `json:"area"`
-	Quran   map[string]string `json:"quran"`
-	Torah   map[string]string `json:"torah"`
-	Bible   map[string]string `json:"bible"`
-	HD      map[string]string `json:"human_design"`
-	Summary string            `json:"summary"`
+// SendLoginEmail sends a one-time sign-in link
+func SendLoginEmail(cfg *Config, name, email, link string, valid time.Duration) error {
+	subject := "Your Scripture Daily sign-in link"
+	body := fmt.Sprintf(`Dear %s,
+
+Use the link below to sign in to your Scripture Daily account. It works once
+and expires in %d minutes.
+
+%s
+
+If you did not ask to sign in, you can ignore this email.
+
+Best regards,
+The Scripture Daily Team
+`, name, int(valid.Minutes()), link)
+
+	return SendEmail(cfg, email, subject, body)
+}
+
+// Options personalise the daily email for one subscriber.
+type Options struct {
+	// Sources limits the passages to these source ids; empty means all.
+	Sources []string
+	// Language selects the email's wording; unknown languages use English.
+	Language string
+	// Tracking, if set, adds an open pixel to the HTML daily email and
+	// sends its links through the click tracker.
+	Tracking *Tracking
+}
+
+// labels is the fixed wording of the daily and digest emails in one
+// language.
+type labels struct {
+	Subject, Heading, Topic, CommonGround, Thanks, Visit, Archive, Unsubscribe string
+	// NoTracking is the text of the tracking opt-out link.
+	NoTracking string
+	// Weekly digest.
+	DigestSubject, DigestHeading, WeekTopics, ReadMore string
+}
+
+var dailyLabels = map[string]labels{
+	"en": {
+		Subject:      "Scripture Daily",
+		Heading:      "Scripture Daily for",
+		Topic:        "TOPIC OF TODAY",
+		CommonGround: "COMMON GROUND",
+		Thanks:       "Thank you for being part of Scripture Daily.",
+		Visit:        "Visit us at",
+		Archive:      "View archive",
+		Unsubscribe:  "To unsubscribe, please contact support@net1io.com",
+		NoTracking:   "Stop tracking when I open these emails",
+
+		DigestSubject: "Scripture Daily weekly digest",
+		DigestHeading: "Your week with Scripture Daily",
+		WeekTopics:    "TOPICS OF THE WEEK",
+		ReadMore:      "Read the full day",
+	},
+	"ms": {
+		Subject:      "Kitab Suci Harian",
+		Heading:      "Kitab Suci Harian untuk",
+		Topic:        "TOPIK HARI INI",
+		CommonGround: "PERSAMAAN",
+		Thanks:       "Terima kasih kerana bersama Scripture Daily.",
+		Visit:        "Layari kami di",
+		Archive:      "Lihat arkib",
+		Unsubscribe:  "Untuk berhenti melanggan, sila hubungi support@net1io.com",
+		NoTracking:   "Berhenti menjejak apabila saya membuka e-mel ini",
+
+		DigestSubject: "Ringkasan mingguan Kitab Suci Harian",
+		DigestHeading: "Minggu anda bersama Scripture Daily",
+		WeekTopics:    "TOPIK MINGGU INI",
+		ReadMore:      "Baca selengkapnya",
+	},
+	"id": {
+		Subject:      "Kitab Suci Harian",
+		Heading:      "Kitab Suci Harian untuk",
+		Topic:        "TOPIK HARI INI",
+		CommonGround: "TITIK TEMU",
+		Thanks:       "Terima kasih telah menjadi bagian dari Scripture Daily.",
+		Visit:        "Kunjungi kami di",
+		Archive:      "Lihat arsip",
+		Unsubscribe:  "Untuk berhenti berlangganan, hubungi support@net1io.com",
+		NoTracking:   "Berhenti melacak saat saya membuka email ini",
+
+		DigestSubject: "Ringkasan mingguan Kitab Suci Harian",
+		DigestHeading: "Minggu Anda bersama Scripture Daily",
+		WeekTopics:    "TOPIK MINGGU INI",
+		ReadMore:      "Baca selengkapnya",
+	},
+}
+
+func labelsFor(language string) labels {
+	if l, ok := dailyLabels[language]; ok {
+		return l
+	}
+	return dailyLabels["en"]
+}
+
+// selectPassages returns the passages for the chosen sources, in payload
+// order. If none of the chosen sources is in the payload, every passage is
+// kept rather than sending an empty email.
+func selectPassages(passages []scripture.Passage, sources []string) []scripture.Passage {
+	if len(sources) == 0 {
+		return passages
+	}
+	want := map[string]bool{}
+	for _, s := range sources {
+		want[s] = true
+	}
+	var out []scripture.Passage
+	for _, p := range passages {
+		if want[p.Source] {
+			out = append(out, p)
+		}
+	}
+	if len(out) == 0 {
+		return passages
+	}
+	return out
 }
 
 // SendDailyScriptureEmail sends the daily scripture to a subscriber
-func SendDailyScriptureEmail(cfg *Config, toEmail string, daily *Daily) error {
-	subject := fmt.Sprintf("Scripture Daily - %s: %s", daily.Date, strings.Title(daily.Area))
+func SendDailyScriptureEmail(cfg *Config, toEmail string, daily *scripture.Daily, opts Options) error {
+	l := labelsFor(opts.Language)
+	subject := fmt.Sprintf("%s - %s: %s", l.Subject, daily.Date, strings.Title(daily.Area))
+
+	selected := selectPassages(daily.Passages, opts.Sources)
+	var passages strings.Builder
+	for i, p := range selected {
+		if i > 0 {
+			passages.WriteString("\n")
+		}
+		fmt.Fprintf(&passages, "%s (%s)\n%s\n", strings.ToUpper(p.Name), p.Ref, p.Text)
+	}
 
-	body := fmt.Sprintf(`Scripture Daily for %s
+	body := fmt.Sprintf(`%s %s
 
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
-TOPIC OF TODAY: %s
+%s: %s
 
 ━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
-QUR'AN (%s)
 %s
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
-TORAH (%s)
 %s
-
-BIBLE (%s)
 %s
 
-HUMAN DESIGN (%s)
+━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
 %s
 
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+%s: %s
+%s: %s/post/%s
+
+---
+Developed by Net1io.com
+Copyright (C) Reserved 2025
 
-COMMON GROUND
 %s
+`,
+		l.Heading, daily.Date,
+		l.Topic, strings.ToUpper(daily.Area),
+		passages.String(),
+		l.CommonGround, daily.Summary,
+		l.Thanks,
+		l.Visit, cfg.SiteURL,
+		l.Archive, cfg.SiteURL, daily.Date,
+		l.Unsubscribe,
+	)
 
-━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+	html, err := dailyHTML(cfg.SiteURL, daily, selected, l, opts.Tracking)
+	if err != nil {
+		return err
+	}
+	if tr := opts.Tracking; tr != nil && tr.Save != nil && tr.Save(tr) != nil {
+		if html, err = dailyHTML(cfg.SiteURL, daily, selected, l, nil); err != nil {
+			return err
+		}
+	}
+	return SendEmailHTML(cfg, toEmail, subject, body, html)
+}
 
-Thank you for being part of Scripture Daily.
+// SendWeeklyDigestEmail sends one email covering several days' payloads,
+// oldest first: the week's topics, then each day's passages with a link to
+// the day on the site.
+func SendWeeklyDigestEmail(cfg *Config, toEmail string, days []scripture.Daily, opts Options) error {
+	if len(days) == 0 {
+		return fmt.Errorf("digest has no days")
+	}
+	l := labelsFor(opts.Language)
+	first, last := days[0].Date, days[len(days)-1].Date
+	subject := fmt.Sprintf("%s - %s to %s", l.DigestSubject, first, last)
+
+	// Topics in the order they were featured, with repeats counted.
+	var topics []string
+	count := map[string]int{}
+	for _, d := range days {
+		if count[d.Area] == 0 {
+			topics = append(topics, d.Area)
+		}
+		count[d.Area]++
+	}
+	var week strings.Builder
+	for _, t := range topics {
+		if count[t] > 1 {
+			fmt.Fprintf(&week, "• %s (%d)\n", strings.Title(t), count[t])
+		} else {
+			fmt.Fprintf(&week, "• %s\n", strings.Title(t))
+		}
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\n%s – %s\n\n", l.DigestHeading, first, last)
+	fmt.Fprintf(&body, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+	fmt.Fprintf(&body, "%s\n%s\n", l.WeekTopics, week.String())
+	for _, d := range days {
+		fmt.Fprintf(&body, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
+		fmt.Fprintf(&body, "%s: %s\n\n", d.Date, strings.ToUpper(d.Area))
+		for _, p := range selectPassages(d.Passages, opts.Sources) {
+			fmt.Fprintf(&body, "%s (%s)\n%s\n\n", strings.ToUpper(p.Name), p.Ref, p.Text)
+		}
+		fmt.Fprintf(&body, "%s: %s/post/%s\n\n", l.ReadMore, cfg.SiteURL, d.Date)
+	}
+	fmt.Fprintf(&body, `━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
+
+%s
 
-Visit us at: https://scripturedaily.com
-View archive: https://scripturedaily.com/post/%s
+%s: %s
 
 ---
 Developed by Net1io.com
 Copyright (C) Reserved 2025
 
-To unsubscribe, please contact support@net1io.com
-`,
-		daily.Date,
-		strings.ToUpper(daily.Area),
-		daily.Quran["ref"], daily.Quran["text"],
-		daily.Torah["ref"], daily.Torah["text"],
-		daily.Bible["ref"], daily.Bible["text"],
-		daily.HD["ref"], daily.HD["text"],
-		daily.Summary,
-		daily.Date,
-	)
+%s
+`, l.Thanks, l.Visit, cfg.SiteURL, l.Unsubscribe)
 
-	return SendEmail(cfg, toEmail, subject, body)
+	return SendEmail(cfg, toEmail, subject, body.String())
 }
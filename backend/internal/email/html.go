@@ -0,0 +1,81 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// Tracking sends one email's opens and clicks through the tracking
+// endpoints of the API at BaseURL, identified by Token.
+type Tracking struct {
+	BaseURL string
+	Token   string
+	// Links are the original URLs of the wrapped links, in order; link n
+	// is served at /t/c/{Token}.{n}. They must be stored with the token
+	// for the redirects to work.
+	Links []string
+	// OptOutURL turns tracking off for the subscriber in one click. It is
+	// shown in the footer and not tracked itself.
+	OptOutURL string
+	// Save stores the email's token and Links once it is rendered, before
+	// it is sent, so its pixel and links work as soon as it arrives. If
+	// Save fails, the email is sent without tracking.
+	Save func(*Tracking) error
+}
+
+// PixelURL is the address of the email's open pixel.
+func (t *Tracking) PixelURL() string {
+	return strings.TrimRight(t.BaseURL, "/") + "/t/o/" + t.Token
+}
+
+// Wrap returns a tracked link that redirects to url.
+func (t *Tracking) Wrap(url string) string {
+	t.Links = append(t.Links, url)
+	return fmt.Sprintf("%s/t/c/%s.%d", strings.TrimRight(t.BaseURL, "/"), t.Token, len(t.Links)-1)
+}
+
+var dailyTemplate = template.Must(template.New("daily").Parse(`<!doctype html>
+<html><body style="margin:0;padding:24px;background:#f6f5f1;font-family:Georgia,serif;color:#222">
+<div style="max-width:600px;margin:0 auto;background:#fff;padding:24px;border-radius:8px">
+<h1 style="font-size:22px;margin:0 0 4px">{{.L.Heading}} {{.Daily.Date}}</h1>
+<p style="font-size:13px;letter-spacing:1px;color:#777;margin:0 0 24px">{{.L.Topic}}: {{.Topic}}</p>
+{{range .Passages}}<h2 style="font-size:15px;margin:24px 0 4px">{{.Name}} <span style="font-weight:normal;color:#777">({{.Ref}})</span></h2>
+<p style="font-size:16px;line-height:1.5;margin:0">{{.Text}}</p>
+{{end}}<h2 style="font-size:13px;letter-spacing:1px;color:#777;margin:32px 0 4px">{{.L.CommonGround}}</h2>
+<p style="font-size:16px;line-height:1.5;margin:0">{{.Daily.Summary}}</p>
+<hr style="border:none;border-top:1px solid #eee;margin:32px 0 16px">
+<p style="font-size:14px">{{.L.Thanks}}</p>
+<p style="font-size:14px"><a href="{{.Archive}}">{{.L.Archive}}</a> &middot; {{.L.Visit}} <a href="{{.Site}}">{{.SiteName}}</a></p>
+<p style="font-size:12px;color:#999">Developed by Net1io.com &middot; Copyright (C) Reserved 2025<br>{{.L.Unsubscribe}}{{if .OptOut}}<br><a href="{{.OptOut}}" style="color:#999">{{.L.NoTracking}}</a>{{end}}</p>
+</div>
+{{if .Pixel}}<img src="{{.Pixel}}" width="1" height="1" alt="" style="display:block;border:0">{{end}}
+</body></html>
+`))
+
+// dailyHTML renders the HTML version of the daily email, linking to the
+// web app at site. With tracking, its links are wrapped and the open pixel
+// is added.
+func dailyHTML(site string, daily *scripture.Daily, passages []scripture.Passage, l labels, tr *Tracking) (string, error) {
+	data := struct {
+		L                    labels
+		Daily                *scripture.Daily
+		Topic                string
+		Passages             []scripture.Passage
+		Archive, Site, Pixel string
+		OptOut, SiteName     string
+	}{L: l, Daily: daily, Topic: strings.ToUpper(daily.Area), Passages: passages,
+		Archive: site + "/post/" + daily.Date, Site: site, SiteName: strings.TrimPrefix(strings.TrimPrefix(site, "https://"), "http://")}
+	if tr != nil {
+		data.Archive, data.Site, data.Pixel = tr.Wrap(data.Archive), tr.Wrap(data.Site), tr.PixelURL()
+		data.OptOut = tr.OptOutURL
+	}
+	var buf bytes.Buffer
+	if err := dailyTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,155 @@
+// Package feed renders RSS 2.0 and Atom 1.0 feeds.
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// Feed is a feed independent of its format.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the site the feed belongs to and Self the feed's own URL,
+	// which also serves as its Atom id.
+	Link, Self string
+	// Updated is when any entry last changed. It is the RSS lastBuildDate
+	// and the Atom updated time.
+	Updated time.Time
+	Items   []Item
+}
+
+// Item is one entry. Link is its permalink and, in Atom, its id.
+type Item struct {
+	Title     string
+	Link      string
+	Published time.Time
+	Updated   time.Time
+	// Content is HTML.
+	Content string
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders f as RSS 2.0.
+func RSS(f Feed) ([]byte, error) {
+	ch := rssChannel{
+		Title:         f.Title,
+		Link:          f.Link,
+		Description:   f.Description,
+		SelfLink:      rssLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+		LastBuildDate: f.Updated.UTC().Format(time.RFC1123Z),
+	}
+	for _, it := range f.Items {
+		ch.Items = append(ch.Items, rssItem{
+			Title:       it.Title,
+			Link:        it.Link,
+			GUID:        rssGUID{IsPermaLink: true, Value: it.Link},
+			PubDate:     it.Published.UTC().Format(time.RFC1123Z),
+			Description: it.Content,
+		})
+	}
+	return marshal(rss{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: ch})
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID       string      `xml:"id"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Link      atomLink    `xml:"link"`
+	Published string      `xml:"published"`
+	Updated   string      `xml:"updated"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Atom renders f as Atom 1.0.
+func Atom(f Feed) ([]byte, error) {
+	out := atomFeed{
+		ID:       f.Self,
+		Title:    f.Title,
+		Subtitle: f.Description,
+		Updated:  f.Updated.UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+		},
+		Author: atomAuthor{Name: f.Title},
+	}
+	for _, it := range f.Items {
+		out.Entries = append(out.Entries, atomEntry{
+			ID:        it.Link,
+			Title:     it.Title,
+			Link:      atomLink{Href: it.Link, Rel: "alternate", Type: "text/html"},
+			Published: it.Published.UTC().Format(time.RFC3339),
+			Updated:   it.Updated.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "html", Value: it.Content},
+		})
+	}
+	return marshal(out)
+}
+
+func marshal(v any) ([]byte, error) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
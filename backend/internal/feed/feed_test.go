@@ -0,0 +1,74 @@
+package feed
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func sample() Feed {
+	// Times are in a zone other than UTC; both formats write UTC.
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	return Feed{
+		Title:       "Scripture Daily: Mercy & Justice",
+		Description: "Daily passages from Qur'an, Torah, Bible and Human Design on a shared theme",
+		Link:        "https://app.example/",
+		Self:        "https://api.example/feed/mercy.rss",
+		Updated:     time.Date(2026, 1, 6, 7, 30, 0, 0, loc),
+		Items: []Item{
+			{
+				Title:     "2026-01-06: Mercy",
+				Link:      "https://app.example/post/2026-01-06",
+				Published: time.Date(2026, 1, 6, 2, 0, 0, 0, loc),
+				Updated:   time.Date(2026, 1, 6, 7, 30, 0, 0, loc),
+				Content:   "<p><strong>Mercy</strong></p>\n<p>Be merciful &amp; kind.</p>\n",
+			},
+			{
+				Title:     "2026-01-05: Justice",
+				Link:      "https://app.example/post/2026-01-05",
+				Published: time.Date(2026, 1, 5, 2, 0, 0, 0, loc),
+				Updated:   time.Date(2026, 1, 5, 2, 0, 0, 0, loc),
+				Content:   "<p>Let justice roll down like waters.</p>\n",
+			},
+		},
+	}
+}
+
+func TestGolden(t *testing.T) {
+	for _, tc := range []struct {
+		file   string
+		render func(Feed) ([]byte, error)
+		empty  bool
+	}{
+		{"feed.rss", RSS, false},
+		{"feed.atom", Atom, false},
+		{"empty.rss", RSS, true},
+		{"empty.atom", Atom, true},
+	} {
+		f := sample()
+		if tc.empty {
+			f.Items, f.Updated = nil, time.Unix(0, 0)
+		}
+		got, err := tc.render(f)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.file, err)
+		}
+		golden := filepath.Join("testdata", tc.file)
+		if *update {
+			if err := os.WriteFile(golden, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%s differs from the golden file:\n%s", tc.file, got)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"@often",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	for _, tc := range []struct {
+		expr, from, want string
+	}{
+		{"* * * * *", "2026-01-01T00:00", "2026-01-01T00:01"},
+		{"30 6 * * *", "2026-01-01T06:30", "2026-01-02T06:30"}, // strictly after
+		{"0 9-11 * * *", "2026-01-01T10:00", "2026-01-01T11:00"},
+		{"0 9-11 * * *", "2026-01-01T11:00", "2026-01-02T09:00"},
+		{"15,45 * * * *", "2026-01-01T00:20", "2026-01-01T00:45"},
+		{"*/20 * * * *", "2026-01-01T00:41", "2026-01-01T01:00"},
+		{"10-30/10 * * * *", "2026-01-01T00:11", "2026-01-01T00:20"},
+		{"5/15 * * * *", "2026-01-01T00:51", "2026-01-01T01:05"},
+		{"0 0 * 2 *", "2026-01-15T12:00", "2026-02-01T00:00"},
+		{"0 0 29 2 *", "2026-01-01T00:00", "2028-02-29T00:00"},
+		{"0 0 * * 7", "2026-01-01T00:00", "2026-01-04T00:00"}, // 7 is Sunday
+		{"0 0 * * 1-5", "2026-01-02T12:00", "2026-01-05T00:00"},
+		{"@hourly", "2026-01-01T00:30", "2026-01-01T01:00"},
+		{"@daily", "2026-01-01T00:30", "2026-01-02T00:00"},
+		{"@weekly", "2026-01-01T00:00", "2026-01-04T00:00"},
+		{"@monthly", "2026-01-15T00:00", "2026-02-01T00:00"},
+		{"@yearly", "2026-01-15T00:00", "2027-01-01T00:00"},
+		// Both day fields restricted: either may match.
+		{"0 0 13 * 5", "2026-01-01T00:00", "2026-01-02T00:00"},
+		{"0 0 13 * 5", "2026-01-10T00:00", "2026-01-13T00:00"},
+		// A */N day field is unrestricted, so the other one decides alone.
+		{"0 0 */2 * 1", "2026-01-01T00:00", "2026-01-05T00:00"},
+		{"0 0 1 * */2", "2026-01-01T00:00", "2026-02-01T00:00"},
+	} {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.expr, err)
+			continue
+		}
+		from, _ := time.Parse("2006-01-02T15:04", tc.from)
+		if got := s.Next(from).Format("2006-01-02T15:04"); got != tc.want {
+			t.Errorf("%q.Next(%s) = %s, want %s", tc.expr, tc.from, got, tc.want)
+		}
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next = %v, want zero time", got)
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	santiago, err := time.LoadLocation("America/Santiago")
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+	for _, tc := range []struct {
+		expr string
+		from time.Time
+		want string
+	}{
+		// 02:30 does not exist on 8 March 2026 and is skipped that day.
+		{"30 2 * * *", time.Date(2026, 3, 8, 0, 0, 0, 0, loc), "2026-03-09T02:30:00-04:00"},
+		{"0 * * * *", time.Date(2026, 3, 8, 1, 0, 0, 0, loc), "2026-03-08T03:00:00-04:00"},
+		// Midnight does not exist on 6 September 2026 in Santiago.
+		{"0 12 * * 0", time.Date(2026, 9, 5, 13, 0, 0, 0, santiago), "2026-09-06T12:00:00-03:00"},
+		// 01:30 happens twice on 1 November 2026: fixed hours fire once...
+		{"30 1 * * *", time.Date(2026, 11, 1, 1, 30, 0, 0, loc), "2026-11-02T01:30:00-05:00"},
+		// ...while hourly schedules fire in both.
+		{"30 * * * *", time.Date(2026, 11, 1, 1, 30, 0, 0, loc), "2026-11-01T01:30:00-05:00"},
+	} {
+		s, err := Parse(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Next(tc.from).Format(time.RFC3339); got != tc.want {
+			t.Errorf("%q.Next(%s) = %s, want %s", tc.expr, tc.from.Format(time.RFC3339), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job is a named task run on a cron schedule.
+type Job struct {
+	Name     string
+	Schedule *Schedule
+	Run      func(ctx context.Context) error
+}
+
+// Run starts every job on its schedule, evaluated in loc, and blocks until
+// ctx is cancelled. A job that is still running when its next slot arrives
+// skips that slot rather than running twice concurrently.
+func Run(ctx context.Context, loc *time.Location, jobs []Job) {
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			runJob(ctx, loc, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func runJob(ctx context.Context, loc *time.Location, job Job) {
+	for {
+		now := time.Now().In(loc)
+		next := job.Schedule.Next(now)
+		if next.IsZero() {
+			log.Printf("[scheduler] %s: schedule %q never fires, stopping", job.Name, job.Schedule)
+			return
+		}
+		log.Printf("[scheduler] %s: next run at %s", job.Name, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		if err := job.Run(ctx); err != nil {
+			log.Printf("[scheduler] %s: failed after %s: %v", job.Name, time.Since(start).Round(time.Millisecond), err)
+			continue
+		}
+		log.Printf("[scheduler] %s: finished in %s", job.Name, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// Lead blocks until this process holds the session-level advisory lock key,
+// then calls fn with a context that is cancelled if ctx ends or the
+// connection holding the lock is lost. After fn returns the lock is released
+// and Lead competes for it again, so a standby replica takes over within
+// retry of the leader dying. Lead returns when ctx is cancelled.
+func Lead(ctx context.Context, dbh *sql.DB, key int64, retry time.Duration, fn func(ctx context.Context)) {
+	for ctx.Err() == nil {
+		conn, err := acquire(ctx, dbh, key)
+		if err != nil {
+			log.Printf("[scheduler] leader election: %v", err)
+		}
+		if conn == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(retry):
+			}
+			continue
+		}
+
+		log.Printf("[scheduler] acquired leadership (advisory lock %d)", key)
+		leaderCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			// Advisory locks live as long as the session, so a dead
+			// connection means another replica may already lead.
+			ticker := time.NewTicker(retry)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-leaderCtx.Done():
+					return
+				case <-ticker.C:
+					if err := conn.PingContext(leaderCtx); err != nil {
+						log.Printf("[scheduler] lost leader connection: %v", err)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+		fn(leaderCtx)
+		cancel()
+
+		unlockCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		_, _ = conn.ExecContext(unlockCtx, `SELECT pg_advisory_unlock($1)`, key)
+		done()
+		conn.Close()
+		log.Printf("[scheduler] released leadership")
+	}
+}
+
+// acquire returns a dedicated connection holding the lock, or nil if
+// another session holds it.
+func acquire(ctx context.Context, dbh *sql.DB, key int64) (*sql.Conn, error) {
+	conn, err := dbh.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !ok {
+		conn.Close()
+		return nil, nil
+	}
+	return conn, nil
+}
@@ -0,0 +1,237 @@
+// Package scripture is the domain model shared by storage, the worker, the
+// API and email: the daily payload, its passages and the verses they are
+// drawn from.
+package scripture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the payload layout written by this code.
+//
+//	1: fixed quran/torah/bible/human_design fields only
+//	2: ordered passages list, with the fixed fields kept for old clients
+const SchemaVersion = 2
+
+// DateLayout is the format of Daily.Date.
+const DateLayout = "2006-01-02"
+
+// LegacySource is one of the traditions that had a dedicated field in
+// schema version 1. Its ID doubles as the JSON field name.
+type LegacySource struct {
+	ID            string
+	Name          string
+	CitationStyle string
+}
+
+// LegacySources are the traditions the site launched with, in their
+// original order.
+var LegacySources = []LegacySource{
+	{ID: "quran", Name: "Qur'an", CitationStyle: "Qur'an {ref}"},
+	{ID: "torah", Name: "Torah", CitationStyle: "{ref}"},
+	{ID: "bible", Name: "Bible", CitationStyle: "{ref}"},
+	{ID: "human_design", Name: "Human Design", CitationStyle: "{ref}"},
+}
+
+// Cite formats ref with a citation style, replacing "{ref}".
+func Cite(style, ref string) string {
+	if style == "" {
+		return ref
+	}
+	return strings.ReplaceAll(style, "{ref}", ref)
+}
+
+// Verse is a single tagged verse from one source.
+type Verse struct {
+	ID     int    `json:"id"`
+	Source string `json:"source"`
+	Ref    string `json:"ref"`
+	Text   string `json:"text"`
+	Topics string `json:"topics"` // comma-separated
+}
+
+// TopicList returns the verse's topics, trimmed, in stored order.
+func (v Verse) TopicList() []string {
+	var out []string
+	for _, t := range strings.Split(v.Topics, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Validate checks that the verse has everything needed to be published.
+func (v Verse) Validate() error {
+	var errs []error
+	if v.Source == "" {
+		errs = append(errs, errors.New("source is required"))
+	}
+	if strings.TrimSpace(v.Ref) == "" {
+		errs = append(errs, errors.New("ref is required"))
+	}
+	if strings.TrimSpace(v.Text) == "" {
+		errs = append(errs, errors.New("text is required"))
+	}
+	if len(v.TopicList()) == 0 {
+		errs = append(errs, errors.New("at least one topic is required"))
+	}
+	return errors.Join(errs...)
+}
+
+// Passage is one tradition's verse within a daily payload.
+type Passage struct {
+	Source   string `json:"source"`
+	Name     string `json:"name"`
+	Ref      string `json:"ref"`
+	Text     string `json:"text"`
+	Citation string `json:"citation"`
+}
+
+// Complete reports whether the passage has both a reference and text.
+func (p Passage) Complete() bool {
+	return p.Ref != "" && p.Text != ""
+}
+
+// Daily is the payload published for one date.
+type Daily struct {
+	SchemaVersion int
+	Date          string
+	Area          string
+	Passages      []Passage
+	Summary       string
+	Meta          map[string]interface{}
+}
+
+// Passage returns the passage for source, if the payload has one.
+func (d Daily) Passage(source string) (Passage, bool) {
+	for _, p := range d.Passages {
+		if p.Source == source {
+			return p, true
+		}
+	}
+	return Passage{}, false
+}
+
+// MissingSources returns the sources whose passage lacks a ref or text.
+func (d Daily) MissingSources() []string {
+	var missing []string
+	for _, p := range d.Passages {
+		if !p.Complete() {
+			missing = append(missing, p.Source)
+		}
+	}
+	return missing
+}
+
+// Validate checks that the payload is fit to publish: a valid date, a
+// topic, at least one passage and no incomplete passages.
+func (d Daily) Validate() error {
+	var errs []error
+	if _, err := time.Parse(DateLayout, d.Date); err != nil {
+		errs = append(errs, fmt.Errorf("bad date %q", d.Date))
+	}
+	if d.Area == "" {
+		errs = append(errs, errors.New("topic (area) is required"))
+	}
+	if len(d.Passages) == 0 {
+		errs = append(errs, errors.New("no passages"))
+	}
+	if missing := d.MissingSources(); len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("incomplete passages for %s", strings.Join(missing, ", ")))
+	}
+	return errors.Join(errs...)
+}
+
+// legacyPassage is the schema version 1 shape of a passage.
+type legacyPassage struct {
+	Ref  string `json:"ref"`
+	Text string `json:"text"`
+}
+
+// dailyJSON is the wire format. The legacy fields are written alongside
+// Passages so clients built for schema version 1 keep working.
+type dailyJSON struct {
+	SchemaVersion int                    `json:"schema_version"`
+	Date          string                 `json:"date"`
+	Area          string                 `json:"area"`
+	Passages      []Passage              `json:"passages"`
+	Quran         *legacyPassage         `json:"quran,omitempty"`
+	Torah         *legacyPassage         `json:"torah,omitempty"`
+	Bible         *legacyPassage         `json:"bible,omitempty"`
+	HD            *legacyPassage         `json:"human_design,omitempty"`
+	Summary       string                 `json:"summary"`
+	Meta          map[string]interface{} `json:"meta,omitempty"`
+}
+
+func (j *dailyJSON) legacyField(id string) **legacyPassage {
+	switch id {
+	case "quran":
+		return &j.Quran
+	case "torah":
+		return &j.Torah
+	case "bible":
+		return &j.Bible
+	case "human_design":
+		return &j.HD
+	}
+	return nil
+}
+
+// MarshalJSON writes the current schema version with legacy fields filled
+// from the matching passages.
+func (d Daily) MarshalJSON() ([]byte, error) {
+	j := dailyJSON{
+		SchemaVersion: SchemaVersion,
+		Date:          d.Date,
+		Area:          d.Area,
+		Passages:      d.Passages,
+		Summary:       d.Summary,
+		Meta:          d.Meta,
+	}
+	if j.Passages == nil {
+		j.Passages = []Passage{}
+	}
+	for _, ls := range LegacySources {
+		if p, ok := d.Passage(ls.ID); ok {
+			*j.legacyField(ls.ID) = &legacyPassage{Ref: p.Ref, Text: p.Text}
+		}
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON reads any known schema version. Version 1 payloads (no
+// schema_version, no passages) are upgraded by deriving passages from the
+// legacy fields. Payloads from a newer schema are rejected.
+func (d *Daily) UnmarshalJSON(b []byte) error {
+	var j dailyJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	if j.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("payload schema version %d is newer than supported version %d", j.SchemaVersion, SchemaVersion)
+	}
+	*d = Daily{
+		SchemaVersion: j.SchemaVersion,
+		Date:          j.Date,
+		Area:          j.Area,
+		Passages:      j.Passages,
+		Summary:       j.Summary,
+		Meta:          j.Meta,
+	}
+	if len(d.Passages) == 0 {
+		for _, ls := range LegacySources {
+			if lp := *j.legacyField(ls.ID); lp != nil {
+				d.Passages = append(d.Passages, Passage{Source: ls.ID, Name: ls.Name, Ref: lp.Ref, Text: lp.Text, Citation: Cite(ls.CitationStyle, lp.Ref)})
+			}
+		}
+	}
+	if d.SchemaVersion == 0 {
+		d.SchemaVersion = 1
+	}
+	return nil
+}
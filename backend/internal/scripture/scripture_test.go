@@ -0,0 +1,127 @@
+package scripture
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalV1(t *testing.T) {
+	const v1 = `{
+		"date": "2026-01-05",
+		"area": "mercy",
+		"quran": {"ref": "2:177", "text": "Righteousness is not turning your faces."},
+		"torah": {"ref": "Leviticus 19:18", "text": "Love your neighbour as yourself."},
+		"bible": {"ref": "Luke 6:36", "text": "Be merciful."},
+		"human_design": {"ref": "Gate 5", "text": ""},
+		"summary": "Mercy is shared."
+	}`
+	var d Daily
+	if err := json.Unmarshal([]byte(v1), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.SchemaVersion != 1 || d.Date != "2026-01-05" || d.Area != "mercy" || d.Summary != "Mercy is shared." {
+		t.Errorf("header = %d %q %q %q", d.SchemaVersion, d.Date, d.Area, d.Summary)
+	}
+	want := []Passage{
+		{Source: "quran", Name: "Qur'an", Ref: "2:177", Text: "Righteousness is not turning your faces.", Citation: "Qur'an 2:177"},
+		{Source: "torah", Name: "Torah", Ref: "Leviticus 19:18", Text: "Love your neighbour as yourself.", Citation: "Leviticus 19:18"},
+		{Source: "bible", Name: "Bible", Ref: "Luke 6:36", Text: "Be merciful.", Citation: "Luke 6:36"},
+		{Source: "human_design", Name: "Human Design", Ref: "Gate 5", Citation: "Gate 5"},
+	}
+	if !reflect.DeepEqual(d.Passages, want) {
+		t.Errorf("passages = %+v, want %+v", d.Passages, want)
+	}
+	if got := d.MissingSources(); !reflect.DeepEqual(got, []string{"human_design"}) {
+		t.Errorf("MissingSources = %v, want [human_design]", got)
+	}
+	if err := d.Validate(); err == nil || !strings.Contains(err.Error(), "incomplete passages for human_design") {
+		t.Errorf("Validate = %v, want incomplete human_design", err)
+	}
+}
+
+func TestRoundTripV2(t *testing.T) {
+	d := Daily{
+		SchemaVersion: SchemaVersion,
+		Date:          "2026-01-05",
+		Area:          "mercy",
+		Passages: []Passage{
+			{Source: "quran", Name: "Qur'an", Ref: "2:177", Text: "Righteousness.", Citation: "Qur'an 2:177"},
+			{Source: "gita", Name: "Bhagavad Gita", Ref: "2:47", Text: "Act without attachment.", Citation: "Gita 2:47"},
+		},
+		Summary: "Mercy is shared.",
+		Meta:    map[string]interface{}{"override": true},
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wire map[string]json.RawMessage
+	if err := json.Unmarshal(b, &wire); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(wire["schema_version"]); got != "2" {
+		t.Errorf("schema_version = %s, want 2", got)
+	}
+	if got := string(wire["quran"]); got != `{"ref":"2:177","text":"Righteousness."}` {
+		t.Errorf("legacy quran = %s", got)
+	}
+	for _, field := range []string{"torah", "bible", "human_design", "gita"} {
+		if _, ok := wire[field]; ok {
+			t.Errorf("unexpected field %q in %s", field, b)
+		}
+	}
+
+	var back Daily
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, d) {
+		t.Errorf("round trip = %+v, want %+v", back, d)
+	}
+	if err := back.Validate(); err != nil {
+		t.Errorf("Validate = %v", err)
+	}
+}
+
+func TestMarshalEmpty(t *testing.T) {
+	b, err := json.Marshal(Daily{Date: "2026-01-05"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"passages":[]`) || !strings.Contains(string(b), `"schema_version":2`) {
+		t.Errorf("Marshal = %s", b)
+	}
+}
+
+func TestUnmarshalNewerSchema(t *testing.T) {
+	var d Daily
+	err := json.Unmarshal([]byte(`{"schema_version": 3, "date": "2026-01-05", "passages": []}`), &d)
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Errorf("Unmarshal = %v, want newer schema error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ok := Passage{Source: "quran", Ref: "2:177", Text: "Righteousness."}
+	for _, tc := range []struct {
+		d    Daily
+		want string // substring of the error, "" for valid
+	}{
+		{Daily{Date: "2026-01-05", Area: "mercy", Passages: []Passage{ok}}, ""},
+		{Daily{Date: "05/01/2026", Area: "mercy", Passages: []Passage{ok}}, "bad date"},
+		{Daily{Date: "2026-01-05", Passages: []Passage{ok}}, "topic (area) is required"},
+		{Daily{Date: "2026-01-05", Area: "mercy"}, "no passages"},
+		{Daily{Date: "2026-01-05", Area: "mercy", Passages: []Passage{ok, {Source: "bible", Ref: "Luke 6:36"}}}, "incomplete passages for bible"},
+	} {
+		err := tc.d.Validate()
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("Validate(%+v) = %v, want nil", tc.d, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("Validate(%+v) = %v, want %q", tc.d, err, tc.want)
+		}
+	}
+}
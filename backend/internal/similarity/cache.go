@@ -0,0 +1,46 @@
+package similarity
+
+import (
+	"sync"
+	"time"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// Cache keeps an Index between requests. Writers call Invalidate after
+// changing verses; MaxAge bounds how stale the index can get from changes
+// it was not told about, such as edits made through another replica or
+// directly in the database.
+type Cache struct {
+	// Load returns every verse to index.
+	Load   func() ([]scripture.Verse, error)
+	MaxAge time.Duration
+
+	mu    sync.Mutex
+	idx   *Index
+	built time.Time
+}
+
+// Get returns the cached index, building it first if there is none or it
+// is older than MaxAge.
+func (c *Cache) Get() (*Index, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.idx != nil && (c.MaxAge <= 0 || time.Since(c.built) < c.MaxAge) {
+		return c.idx, nil
+	}
+	verses, err := c.Load()
+	if err != nil {
+		return nil, err
+	}
+	c.idx, c.built = New(verses), time.Now()
+	return c.idx, nil
+}
+
+// Invalidate drops the cached index so the next Get rebuilds it. A build
+// in progress finishes first, so its possibly stale result is dropped too.
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	c.idx = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,203 @@
+// Package similarity ranks verses by textual similarity with Okapi BM25, so
+// curators can find related passages and get topic suggestions for new ones.
+package similarity
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Match is a verse and its similarity to the query.
+type Match struct {
+	Verse scripture.Verse `json:"verse"`
+	Score float64         `json:"score"`
+}
+
+// Index is an in-memory BM25 index over verse text.
+type Index struct {
+	verses []scripture.Verse
+	terms  []map[string]int // term frequencies per verse
+	length []int
+	avgLen float64
+	df     map[string]int
+}
+
+// New indexes verses. The index is immutable; build a new one when the
+// verses change.
+func New(verses []scripture.Verse) *Index {
+	idx := &Index{verses: verses, df: map[string]int{}}
+	total := 0
+	for _, v := range verses {
+		tf := map[string]int{}
+		n := 0
+		for _, t := range Tokenize(v.Text) {
+			tf[t]++
+			n++
+		}
+		for t := range tf {
+			idx.df[t]++
+		}
+		idx.terms = append(idx.terms, tf)
+		idx.length = append(idx.length, n)
+		total += n
+	}
+	if len(verses) > 0 {
+		idx.avgLen = float64(total) / float64(len(verses))
+	}
+	return idx
+}
+
+func (idx *Index) idf(term string) float64 {
+	n, df := float64(len(idx.verses)), float64(idx.df[term])
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// Search returns up to limit verses ranked by BM25 score against text,
+// skipping the verse with ID exclude (use 0 to keep all) and verses that
+// share no terms with text.
+func (idx *Index) Search(text string, exclude, limit int) []Match {
+	query := map[string]bool{}
+	for _, t := range Tokenize(text) {
+		query[t] = true
+	}
+	var out []Match
+	for i, v := range idx.verses {
+		if v.ID == exclude && exclude != 0 {
+			continue
+		}
+		score := 0.0
+		for t := range query {
+			f := float64(idx.terms[i][t])
+			if f == 0 {
+				continue
+			}
+			norm := 1 - b + b*float64(idx.length[i])/idx.avgLen
+			score += idx.idf(t) * f * (k1 + 1) / (f + k1*norm)
+		}
+		if score > 0 {
+			out = append(out, Match{Verse: v, Score: math.Round(score*1000) / 1000})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// Similar returns the verses most similar to v.
+func (idx *Index) Similar(v scripture.Verse, limit int) []Match {
+	return idx.Search(v.Text, v.ID, limit)
+}
+
+// Suggestion is a proposed topic for a verse, with the neighbours that
+// support it.
+type Suggestion struct {
+	Topic string  `json:"topic"`
+	Score float64 `json:"score"`
+	// Sources lists the traditions whose neighbours carry the topic.
+	Sources  []string `json:"sources"`
+	VerseIDs []int    `json:"verse_ids"`
+}
+
+// SuggestTopics proposes topics for v from the topics of its nearest tagged
+// verses, considering up to neighbours of them. Each neighbour votes for its
+// topics with its score, and a topic's total is multiplied by the number of
+// traditions voting for it, since themes shared across traditions are what
+// the site is built around.
+func (idx *Index) SuggestTopics(v scripture.Verse, neighbours, limit int) []Suggestion {
+	var tagged []Match
+	for _, m := range idx.Similar(v, 0) {
+		if len(m.Verse.TopicList()) > 0 {
+			tagged = append(tagged, m)
+		}
+		if len(tagged) == neighbours {
+			break
+		}
+	}
+
+	byTopic := map[string]*Suggestion{}
+	var order []string
+	for _, m := range tagged {
+		for _, t := range m.Verse.TopicList() {
+			s := byTopic[t]
+			if s == nil {
+				s = &Suggestion{Topic: t}
+				byTopic[t] = s
+				order = append(order, t)
+			}
+			s.Score += m.Score
+			s.VerseIDs = append(s.VerseIDs, m.Verse.ID)
+			if !contains(s.Sources, m.Verse.Source) {
+				s.Sources = append(s.Sources, m.Verse.Source)
+			}
+		}
+	}
+	out := make([]Suggestion, 0, len(order))
+	for _, t := range order {
+		s := byTopic[t]
+		s.Score = math.Round(s.Score*float64(len(s.Sources))*1000) / 1000
+		out = append(out, *s)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// stopwords are common English words that carry no topical meaning,
+// including the archaic forms found in scripture translations.
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`a about all also an and any are as at be been but by can do does
+		for from has have he her him his how i if in into is it its itself may me my no nor not of on
+		one or our out shall she so than that the their them then there these they this those thou thee
+		thy thine to unto up upon us was we were what when which who whom will with ye you your yours`) {
+		stopwords[w] = true
+	}
+}
+
+// Tokenize lower-cases text, splits it into words, drops stopwords and
+// strips common English suffixes so "giving" and "gives" match "give".
+func Tokenize(text string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(w) < 2 || stopwords[w] {
+			continue
+		}
+		out = append(out, stem(w))
+	}
+	return out
+}
+
+func stem(w string) string {
+	for _, suffix := range []string{"ness", "ing", "eth", "est", "ed", "es", "ly", "s", "e"} {
+		if strings.HasSuffix(w, suffix) && len(w)-len(suffix) >= 3 {
+			return strings.TrimSuffix(w, suffix)
+		}
+	}
+	return w
+}
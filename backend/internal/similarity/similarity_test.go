@@ -0,0 +1,116 @@
+package similarity
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/your/module/internal/scripture"
+)
+
+var corpus = []scripture.Verse{
+	{ID: 1, Source: "quran", Text: "Be patient, for patience is a light, and God is with the patient.", Topics: "patience"},
+	{ID: 2, Source: "bible", Text: "Let patience have its perfect work, lacking nothing.", Topics: "patience,faith"},
+	{ID: 3, Source: "torah", Text: "Open your hand wide to the poor and give freely.", Topics: "generosity"},
+	{ID: 4, Source: "bible", Text: "Give, and it will be given to you; the giving hand is blessed.", Topics: "generosity"},
+	{ID: 5, Source: "human_design", Text: "Waiting with patience brings clarity.", Topics: ""},
+	{ID: 6, Source: "quran", Text: "Establish justice and do not be unjust.", Topics: "justice"},
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Giving gives; the Giver GIVETH unto thee, patiently!")
+	want := []string{"giv", "giv", "giver", "giv", "patient"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+}
+
+func ids(matches []Match) []int {
+	out := []int{}
+	for _, m := range matches {
+		out = append(out, m.Verse.ID)
+	}
+	return out
+}
+
+func TestSearch(t *testing.T) {
+	idx := New(corpus)
+	for _, tc := range []struct {
+		query   string
+		exclude int
+		limit   int
+		want    []int
+	}{
+		// Each verse has "patience" once, so the shortest ranks first.
+		{"patience", 0, 0, []int{5, 1, 2}},
+		{"patience", 5, 0, []int{1, 2}},
+		{"patience", 0, 1, []int{5}},
+		{"give to the poor", 0, 0, []int{3, 4}},
+		{"unrelated words only", 0, 0, []int{}},
+	} {
+		if got := ids(idx.Search(tc.query, tc.exclude, tc.limit)); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Search(%q, %d, %d) = %v, want %v", tc.query, tc.exclude, tc.limit, got, tc.want)
+		}
+	}
+
+	matches := idx.Search("patience", 0, 0)
+	for i := 1; i < len(matches); i++ {
+		if matches[i].Score > matches[i-1].Score {
+			t.Errorf("scores not descending: %v", matches)
+		}
+	}
+}
+
+func TestSuggestTopics(t *testing.T) {
+	idx := New(corpus)
+	got := idx.SuggestTopics(corpus[4], 8, 0)
+	if len(got) != 2 {
+		t.Fatalf("SuggestTopics = %+v, want patience and faith", got)
+	}
+	// patience is carried by two traditions, faith by one.
+	if got[0].Topic != "patience" || !reflect.DeepEqual(got[0].Sources, []string{"quran", "bible"}) ||
+		!reflect.DeepEqual(got[0].VerseIDs, []int{1, 2}) {
+		t.Errorf("first suggestion = %+v, want patience from quran 1 and bible 2", got[0])
+	}
+	if got[1].Topic != "faith" || got[1].Score >= got[0].Score {
+		t.Errorf("second suggestion = %+v, want faith below patience", got[1])
+	}
+}
+
+func TestCache(t *testing.T) {
+	loads := 0
+	verses := corpus[:2]
+	c := &Cache{Load: func() ([]scripture.Verse, error) {
+		loads++
+		return verses, nil
+	}}
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("%d loads, want 1", loads)
+	}
+
+	verses = corpus
+	c.Invalidate()
+	idx, _ := c.Get()
+	if loads != 2 || len(ids(idx.Search("give", 0, 0))) == 0 {
+		t.Errorf("index was not rebuilt after Invalidate (%d loads)", loads)
+	}
+
+	c.MaxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	c.Get()
+	if loads != 3 {
+		t.Errorf("%d loads, want 3 after MaxAge", loads)
+	}
+
+	c.Invalidate()
+	c.Load = func() ([]scripture.Verse, error) { return nil, errors.New("db down") }
+	if _, err := c.Get(); err == nil {
+		t.Error("Get did not return the load error")
+	}
+}
@@ -0,0 +1,198 @@
+// Package stripe verifies and decodes Stripe webhook events. It covers only
+// what the subscription flow needs, without the Stripe SDK.
+package stripe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how far a signature's timestamp may be from now,
+// matching Stripe's own libraries.
+const DefaultTolerance = 5 * time.Minute
+
+// Event types the webhook handles.
+const (
+	CheckoutSessionCompleted = "checkout.session.completed"
+	InvoicePaid              = "invoice.paid"
+	InvoicePaymentFailed     = "invoice.payment_failed"
+	SubscriptionCreated      = "customer.subscription.created"
+	SubscriptionUpdated      = "customer.subscription.updated"
+	SubscriptionDeleted      = "customer.subscription.deleted"
+)
+
+var (
+	ErrBadHeader   = errors.New("malformed Stripe-Signature header")
+	ErrNoSignature = errors.New("no signature matches the payload")
+	ErrTimestamp   = errors.New("signature timestamp is outside the tolerance")
+)
+
+// Verify checks the Stripe-Signature header of a webhook payload: one of
+// its v1 signatures must be the HMAC-SHA256 of "<t>.<payload>" under
+// secret, and t must be within tolerance of now, which stops old requests
+// from being replayed.
+func Verify(payload []byte, header, secret string, tolerance time.Duration, now time.Time) error {
+	var ts int64
+	var sigs [][]byte
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return ErrBadHeader
+			}
+			ts = n
+		case "v1":
+			sig, err := hex.DecodeString(v)
+			if err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return ErrBadHeader
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return ErrTimestamp
+	}
+	want := mac(payload, secret, ts)
+	for _, sig := range sigs {
+		if hmac.Equal(sig, want) {
+			return nil
+		}
+	}
+	return ErrNoSignature
+}
+
+// Sign returns a Stripe-Signature header for payload, for sending locally
+// signed events to the webhook.
+func Sign(payload []byte, secret string, t time.Time) string {
+	return fmt.Sprintf("t=%d,v1=%s", t.Unix(), hex.EncodeToString(mac(payload, secret, t.Unix())))
+}
+
+func mac(payload []byte, secret string, ts int64) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%d.", ts)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+// Event is a webhook event. Data.Object is decoded according to Type.
+type Event struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Created  int64  `json:"created"`
+	Livemode bool   `json:"livemode"`
+	Data     struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// CheckoutSession is the object of checkout.session.completed. Payment
+// Links set ClientReferenceID from the link's client_reference_id query
+// parameter, which the web app fills with the subscriber id.
+type CheckoutSession struct {
+	ID                string `json:"id"`
+	Customer          string `json:"customer"`
+	Subscription      string `json:"subscription"`
+	ClientReferenceID string `json:"client_reference_id"`
+	CustomerEmail     string `json:"customer_email"`
+	CustomerDetails   struct {
+		Email string `json:"email"`
+		Name  string `json:"name"`
+	} `json:"customer_details"`
+	PaymentStatus string `json:"payment_status"`
+}
+
+// Email is the payer's address.
+func (s CheckoutSession) Email() string {
+	if s.CustomerDetails.Email != "" {
+		return s.CustomerDetails.Email
+	}
+	return s.CustomerEmail
+}
+
+// Invoice is the object of invoice.paid and invoice.payment_failed.
+type Invoice struct {
+	ID            string `json:"id"`
+	Customer      string `json:"customer"`
+	Subscription  string `json:"subscription"`
+	CustomerEmail string `json:"customer_email"`
+	AmountPaid    int64  `json:"amount_paid"`
+	Currency      string `json:"currency"`
+	Lines         struct {
+		Data []struct {
+			Period struct {
+				Start int64 `json:"start"`
+				End   int64 `json:"end"`
+			} `json:"period"`
+		} `json:"data"`
+	} `json:"lines"`
+}
+
+// PeriodEnd is the end of the service period the invoice pays for: the
+// latest end of its line items. It is zero if the invoice has none.
+func (inv Invoice) PeriodEnd() time.Time {
+	var end int64
+	for _, l := range inv.Lines.Data {
+		end = max(end, l.Period.End)
+	}
+	return unix(end)
+}
+
+// Subscription is the object of customer.subscription.* events.
+type Subscription struct {
+	ID       string `json:"id"`
+	Customer string `json:"customer"`
+	// Status is trialing, active, past_due, unpaid, canceled, incomplete,
+	// incomplete_expired or paused.
+	Status           string `json:"status"`
+	CurrentPeriodEnd int64  `json:"current_period_end"`
+	// TrialEnd is when a trial ends, or 0 without one.
+	TrialEnd int64 `json:"trial_end"`
+	// Newer API versions report the period on each item instead.
+	Items struct {
+		Data []struct {
+			CurrentPeriodEnd int64 `json:"current_period_end"`
+		} `json:"data"`
+	} `json:"items"`
+}
+
+// PeriodEnd is the end of the current period, or zero if unknown.
+func (s Subscription) PeriodEnd() time.Time {
+	end := s.CurrentPeriodEnd
+	for _, it := range s.Items.Data {
+		end = max(end, it.CurrentPeriodEnd)
+	}
+	return unix(end)
+}
+
+func unix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0).UTC()
+}
+
+// Parse decodes a verified webhook payload.
+func Parse(payload []byte) (*Event, error) {
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return nil, err
+	}
+	if ev.ID == "" || ev.Type == "" {
+		return nil, fmt.Errorf("event has no id or type")
+	}
+	return &ev, nil
+}
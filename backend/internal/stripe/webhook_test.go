@@ -0,0 +1,69 @@
+package stripe
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const secret = "whsec_test"
+
+func fixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "fixtures", "stripe", name+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestVerify(t *testing.T) {
+	payload := fixture(t, CheckoutSessionCompleted)
+	now := time.Unix(1760000000, 0)
+	signed := Sign(payload, secret, now)
+	other := hex.EncodeToString(mac(payload, "whsec_other", now.Unix()))
+
+	for _, tc := range []struct {
+		name    string
+		payload []byte
+		header  string
+		now     time.Time
+		want    error
+	}{
+		{"valid", payload, signed, now, nil},
+		{"valid at the edge of the tolerance", payload, signed, now.Add(DefaultTolerance), nil},
+		{"clock behind the sender", payload, signed, now.Add(-time.Minute), nil},
+		{"tampered body", append([]byte(" "), payload...), signed, now, ErrNoSignature},
+		{"wrong secret", payload, fmt.Sprintf("t=%d,v1=%s", now.Unix(), other), now, ErrNoSignature},
+		{"expired timestamp", payload, signed, now.Add(DefaultTolerance + time.Second), ErrTimestamp},
+		{"future timestamp", payload, signed, now.Add(-DefaultTolerance - time.Second), ErrTimestamp},
+		// During secret rotation Stripe signs with both secrets.
+		{"multiple v1, second matches", payload, fmt.Sprintf("t=%d,v1=%s,%s", now.Unix(), other, signed[len("t=1760000000,"):]), now, nil},
+		{"multiple v1, none match", payload, fmt.Sprintf("t=%d,v1=%s,v1=%s", now.Unix(), other, other), now, ErrNoSignature},
+		{"v0 ignored", payload, fmt.Sprintf("t=%d,v0=%s", now.Unix(), signed[len("t=1760000000,v1="):]), now, ErrBadHeader},
+		{"no timestamp", payload, signed[len("t=1760000000,"):], now, ErrBadHeader},
+		{"bad timestamp", payload, "t=soon," + signed[len("t=1760000000,"):], now, ErrBadHeader},
+		{"empty header", payload, "", now, ErrBadHeader},
+	} {
+		if err := Verify(tc.payload, tc.header, secret, DefaultTolerance, tc.now); !errors.Is(err, tc.want) {
+			t.Errorf("%s: Verify = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	ev, err := Parse(fixture(t, InvoicePaid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID != "evt_test_invoice_paid_1" || ev.Type != InvoicePaid || ev.Created != 1760000060 {
+		t.Errorf("Parse = %+v", ev)
+	}
+	if _, err := Parse([]byte(`{"type":"invoice.paid"}`)); err == nil {
+		t.Error("Parse accepted an event without an id")
+	}
+}
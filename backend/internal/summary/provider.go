@@ -0,0 +1,212 @@
+package summary
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider writes a summary for a day's passages. Implementations may be
+// slow or unavailable; callers fall back to the template summary on error.
+type Provider interface {
+	// Name identifies the provider in payload meta and logs.
+	Name() string
+	Summarize(ctx context.Context, in Input) (string, error)
+}
+
+// ChatProvider calls an OpenAI-compatible chat completions endpoint, such as
+// a self-hosted llama.cpp or vLLM server.
+type ChatProvider struct {
+	// BaseURL is the API root, e.g. "http://localhost:8081/v1";
+	// "/chat/completions" is appended.
+	BaseURL string
+	Model   string
+	APIKey  string
+	Timeout time.Duration
+	Client  *http.Client
+}
+
+const systemPrompt = `You write the short daily reflection for an interfaith scripture newsletter. ` +
+	`Given a theme and one passage from each tradition, write 2 to 4 sentences in plain prose that draw out ` +
+	`what the passages share while respecting each tradition. Refer to passages by tradition and reference. ` +
+	`Do not quote more than a few words, rank the traditions, give advice on religious practice, or add links, ` +
+	`headings or lists.`
+
+func (p *ChatProvider) Name() string { return "chat:" + p.Model }
+
+func (p *ChatProvider) Summarize(ctx context.Context, in Input) (string, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	var user strings.Builder
+	fmt.Fprintf(&user, "Theme: %s\n", in.Topic)
+	if in.Blurb != "" {
+		fmt.Fprintf(&user, "Editor's note: %s\n", in.Blurb)
+	}
+	for _, ps := range in.Passages {
+		fmt.Fprintf(&user, "\n%s (%s): %s\n", ps.Name, ps.Ref, ps.Text)
+	}
+	body, err := json.Marshal(map[string]any{
+		"model": p.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": systemPrompt},
+			{"role": "user", "content": user.String()},
+		},
+		"temperature": 0.7,
+		"max_tokens":  300,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(p.BaseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("chat completions returned %s: %.200s", resp.Status, raw)
+	}
+
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", fmt.Errorf("decoding chat completions response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("chat completions response has no choices")
+	}
+	if out.Choices[0].FinishReason == "length" {
+		return "", fmt.Errorf("summary was cut off at the token limit")
+	}
+	return strings.TrimSpace(out.Choices[0].Message.Content), nil
+}
+
+// Cache stores provider output per date. Entries are keyed by a hash of the
+// input as well, so a date whose passages change is summarized again.
+type Cache interface {
+	GetSummary(date, key string) (text string, ok bool, err error)
+	PutSummary(date, key, provider, text string) error
+}
+
+type cached struct {
+	Provider
+	cache   Cache
+	blocked []string
+}
+
+// Cached wraps p so each distinct input is summarized once, and so every
+// summary it returns passes Safe with the extra blocked phrases. Only safe
+// output is cached; cached text is checked again, so a newly blocked phrase
+// also applies to summaries written before it was added.
+func Cached(p Provider, c Cache, blocked []string) Provider {
+	return cached{Provider: p, cache: c, blocked: blocked}
+}
+
+func (c cached) Summarize(ctx context.Context, in Input) (string, error) {
+	key := inputKey(c.Name(), in)
+	if text, ok, err := c.cache.GetSummary(in.Date, key); err != nil {
+		return "", fmt.Errorf("reading summary cache: %w", err)
+	} else if ok {
+		if err := Safe(text, in, c.blocked); err != nil {
+			return "", fmt.Errorf("cached summary: %w", err)
+		}
+		return text, nil
+	}
+	text, err := c.Provider.Summarize(ctx, in)
+	if err != nil {
+		return "", err
+	}
+	if err := Safe(text, in, c.blocked); err != nil {
+		return "", err
+	}
+	if err := c.cache.PutSummary(in.Date, key, c.Name(), text); err != nil {
+		return "", fmt.Errorf("writing summary cache: %w", err)
+	}
+	return text, nil
+}
+
+func inputKey(provider string, in Input) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s", provider, in.Date, in.Topic, in.Blurb)
+	for _, p := range in.Passages {
+		fmt.Fprintf(h, "\x00%s\x00%s\x00%s", p.Source, p.Ref, p.Text)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// MaxLength is the longest generated summary accepted, in characters.
+const MaxLength = 1200
+
+var builtinBlocked = []string{
+	// Refusals and self-references.
+	"as an ai", "language model", "i cannot", "i can't help",
+	// Links and markup.
+	"http://", "https://", "www.", "<", "```", "# ",
+	// Ranking or disparaging traditions.
+	"superior to", "inferior to", "the only true", "false religion", "infidel", "heathen", "heretic",
+	// Profanity.
+	"shit", "fuck",
+}
+
+// Safe is the post-check applied to generated summaries before they are
+// published. It rejects empty or overlong text, refusals, links, markup,
+// the built-in and extra blocked phrases (lower case), and text that does
+// not mention any of the passages.
+func Safe(text string, in Input, blocked []string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("generated summary is empty")
+	}
+	if n := len([]rune(text)); n > MaxLength {
+		return fmt.Errorf("generated summary is %d characters, over %d", n, MaxLength)
+	}
+	lower := strings.ToLower(text)
+	for _, list := range [][]string{builtinBlocked, blocked} {
+		for _, phrase := range list {
+			if phrase != "" && strings.Contains(lower, phrase) {
+				return fmt.Errorf("generated summary contains blocked phrase %q", phrase)
+			}
+		}
+	}
+	for _, p := range in.Passages {
+		if strings.Contains(lower, strings.ToLower(p.Name)) || (p.Ref != "" && strings.Contains(text, p.Ref)) {
+			return nil
+		}
+	}
+	if len(in.Passages) > 0 {
+		return fmt.Errorf("generated summary does not mention any of the passages")
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package summary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/your/module/internal/scripture"
+)
+
+var testInput = Input{
+	Date:  "2025-03-01",
+	Topic: "mercy",
+	Blurb: "Mercy is asked of the strong.",
+	Passages: []scripture.Passage{
+		{Source: "quran", Name: "Qur'an", Ref: "21:107", Text: "We have not sent you except as a mercy to the worlds."},
+		{Source: "bible", Name: "Bible", Ref: "Matt 5:7", Text: "Blessed are the merciful, for they shall obtain mercy."},
+	},
+}
+
+const goodReply = "The Qur'an (21:107) and the Bible (Matt 5:7) both tie mercy to the one who shows it."
+
+// chatServer answers chat completions requests with reply, recording each
+// decoded request body.
+func chatServer(t *testing.T, status int, reply string, requests *[]map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("request %s %s, want POST /v1/chat/completions", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer key")
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", got)
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if requests != nil {
+			*requests = append(*requests, body)
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": map[string]string{"content": reply}, "finish_reason": "stop"}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChatProviderRequest(t *testing.T) {
+	var requests []map[string]any
+	srv := chatServer(t, http.StatusOK, "  "+goodReply+"\n", &requests)
+	p := &ChatProvider{BaseURL: srv.URL + "/v1/", Model: "llama", APIKey: "key", Timeout: time.Second}
+
+	text, err := p.Summarize(context.Background(), testInput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != goodReply {
+		t.Errorf("summary = %q, want %q", text, goodReply)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("%d requests, want 1", len(requests))
+	}
+	body := requests[0]
+	if body["model"] != "llama" {
+		t.Errorf("model = %v, want llama", body["model"])
+	}
+	messages, _ := body["messages"].([]any)
+	if len(messages) != 2 {
+		t.Fatalf("%d messages, want 2", len(messages))
+	}
+	system, _ := messages[0].(map[string]any)
+	user, _ := messages[1].(map[string]any)
+	if system["role"] != "system" || system["content"] != systemPrompt {
+		t.Errorf("first message = %v, want the system prompt", system)
+	}
+	content, _ := user["content"].(string)
+	for _, want := range []string{"Theme: mercy", "Editor's note: Mercy is asked of the strong.",
+		"Qur'an (21:107): We have not sent you", "Bible (Matt 5:7): Blessed are the merciful"} {
+		if user["role"] != "user" || !strings.Contains(content, want) {
+			t.Errorf("user message %q does not contain %q", content, want)
+		}
+	}
+}
+
+func TestChatProviderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+	p := &ChatProvider{BaseURL: srv.URL, Model: "llama", Timeout: 50 * time.Millisecond}
+
+	start := time.Now()
+	_, err := p.Summarize(context.Background(), testInput)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want a deadline exceeded error", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Summarize took %v despite a 50ms timeout", d)
+	}
+}
+
+func TestChatProviderErrors(t *testing.T) {
+	for name, srv := range map[string]*httptest.Server{
+		"server error": chatServer(t, http.StatusInternalServerError, goodReply, nil),
+		"cut off": httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"choices":[{"message":{"content":"The Qur'an"},"finish_reason":"length"}]}`))
+		})),
+		"no choices": httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"choices":[]}`))
+		})),
+	} {
+		p := &ChatProvider{BaseURL: srv.URL + "/v1", Model: "llama", APIKey: "key"}
+		if text, err := p.Summarize(context.Background(), testInput); err == nil {
+			t.Errorf("%s: got summary %q, want an error", name, text)
+		}
+		srv.Close()
+	}
+}
+
+type memCache map[string]string
+
+func (c memCache) GetSummary(date, key string) (string, bool, error) {
+	text, ok := c[date+"/"+key]
+	return text, ok, nil
+}
+
+func (c memCache) PutSummary(date, key, provider, text string) error {
+	c[date+"/"+key] = text
+	return nil
+}
+
+// TestCachedFallback checks that failed or unsafe replies are reported as
+// errors, so the worker falls back to a template, and are never cached.
+func TestCachedFallback(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		status  int
+		reply   string
+		blocked []string
+	}{
+		{"server error", http.StatusBadGateway, goodReply, nil},
+		{"empty", http.StatusOK, " ", nil},
+		{"link", http.StatusOK, goodReply + " See https://example.com.", nil},
+		{"refusal", http.StatusOK, "As an AI language model I cannot comment on the Qur'an.", nil},
+		{"no passage", http.StatusOK, "Mercy matters.", nil},
+		{"too long", http.StatusOK, goodReply + strings.Repeat(" Mercy.", MaxLength/7), nil},
+		{"blocklist", http.StatusOK, goodReply, []string{"both tie"}},
+	} {
+		var requests []map[string]any
+		srv := chatServer(t, tc.status, tc.reply, &requests)
+		cache := memCache{}
+		p := Cached(&ChatProvider{BaseURL: srv.URL + "/v1", Model: "llama", APIKey: "key"}, cache, tc.blocked)
+		for i := 0; i < 2; i++ {
+			if text, err := p.Summarize(context.Background(), testInput); err == nil {
+				t.Errorf("%s: got summary %q, want an error", tc.name, text)
+			}
+		}
+		if len(cache) != 0 {
+			t.Errorf("%s: cached %v", tc.name, cache)
+		}
+		if len(requests) != 2 {
+			t.Errorf("%s: %d requests, want 2 (failures are retried)", tc.name, len(requests))
+		}
+	}
+}
+
+func TestCached(t *testing.T) {
+	var requests []map[string]any
+	srv := chatServer(t, http.StatusOK, goodReply, &requests)
+	cache := memCache{}
+	p := Cached(&ChatProvider{BaseURL: srv.URL + "/v1", Model: "llama", APIKey: "key"}, cache, nil)
+
+	for i := 0; i < 2; i++ {
+		text, err := p.Summarize(context.Background(), testInput)
+		if err != nil || text != goodReply {
+			t.Fatalf("call %d: summary = %q, %v", i, text, err)
+		}
+	}
+	if len(requests) != 1 {
+		t.Errorf("%d requests, want 1 (the second call is cached)", len(requests))
+	}
+
+	changed := testInput
+	changed.Topic = "justice"
+	if _, err := p.Summarize(context.Background(), changed); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("%d requests, want 2 (a new input is summarized again)", len(requests))
+	}
+
+	// A phrase blocked later also rejects the cached summary.
+	p = Cached(&ChatProvider{BaseURL: srv.URL + "/v1", Model: "llama", APIKey: "key"}, cache, []string{"both tie"})
+	if text, err := p.Summarize(context.Background(), testInput); err == nil {
+		t.Errorf("got cached summary %q despite the blocklist", text)
+	}
+}
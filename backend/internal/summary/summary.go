@@ -0,0 +1,166 @@
+// Package summary writes the "Common Ground" text of a daily payload from
+// editor-managed text templates and per-topic blurbs.
+package summary
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/your/module/internal/scripture"
+)
+
+// Template is a text/template body that renders a summary. Topic-specific
+// templates are preferred over generic ones (Topic == "").
+type Template struct {
+	ID      int    `json:"id"`
+	Topic   string `json:"topic"`
+	Body    string `json:"body"`
+	Enabled bool   `json:"enabled"`
+}
+
+// Blurb is an editorial sentence or two about a topic.
+type Blurb struct {
+	ID    int    `json:"id"`
+	Topic string `json:"topic"`
+	Body  string `json:"body"`
+}
+
+// Input is what a template can reference:
+//
+//	{{.Topic}} {{.Date}} {{.Blurb}} {{.Passages}}
+//	{{title .Topic}} {{refs .Passages}} {{citations .Passages}}
+//	{{number (len .Passages)}}
+//	{{with passage "quran"}}{{.Name}} {{.Ref}} {{.Text}} {{.Citation}}{{end}}
+//	{{truncate 80 .Text}}
+type Input struct {
+	Date     string
+	Topic    string
+	Blurb    string
+	Passages []scripture.Passage
+}
+
+// Default is the original fixed summary, used when no template applies or
+// rendering fails.
+const Default = `Today's theme is '{{.Topic}}'. Each tradition highlights this value: {{refs .Passages}}.`
+
+var numberWords = []string{"no", "one", "two", "three", "four", "five", "six",
+	"seven", "eight", "nine", "ten", "eleven", "twelve"}
+
+func funcs(in Input) template.FuncMap {
+	return template.FuncMap{
+		"title": func(s string) string {
+			if s == "" {
+				return s
+			}
+			return strings.ToUpper(s[:1]) + s[1:]
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		// refs renders "Qur'an (2:177), Torah (Deut 15:7), ...".
+		"refs": func(ps []scripture.Passage) string {
+			out := make([]string, len(ps))
+			for i, p := range ps {
+				out[i] = p.Name + " (" + p.Ref + ")"
+			}
+			return strings.Join(out, ", ")
+		},
+		// citations renders "Qur'an 2:177, Deut 15:7 and Prov 11:24".
+		"citations": func(ps []scripture.Passage) string {
+			out := make([]string, len(ps))
+			for i, p := range ps {
+				out[i] = p.Citation
+			}
+			if len(out) < 2 {
+				return strings.Join(out, "")
+			}
+			return strings.Join(out[:len(out)-1], ", ") + " and " + out[len(out)-1]
+		},
+		"passage": func(source string) *scripture.Passage {
+			for _, p := range in.Passages {
+				if p.Source == source {
+					p := p
+					return &p
+				}
+			}
+			return nil
+		},
+		// number spells out small counts: "four traditions".
+		"number": func(n int) string {
+			if n >= 0 && n < len(numberWords) {
+				return numberWords[n]
+			}
+			return fmt.Sprint(n)
+		},
+		"truncate": func(n int, s string) string {
+			r := []rune(s)
+			if len(r) <= n {
+				return s
+			}
+			return strings.TrimSpace(string(r[:n])) + "…"
+		},
+	}
+}
+
+// Render executes a template body against in. Runs of whitespace in the
+// result are collapsed so templates can be written across several lines.
+func Render(body string, in Input) (string, error) {
+	t, err := template.New("summary").Funcs(funcs(in)).Parse(body)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, in); err != nil {
+		return "", err
+	}
+	out := strings.Join(strings.Fields(buf.String()), " ")
+	if out == "" {
+		return "", fmt.Errorf("template rendered an empty summary")
+	}
+	return out, nil
+}
+
+// Variants returns the enabled templates to rotate through for topic:
+// the topic's own templates if it has any, otherwise the generic ones.
+func Variants(templates []Template, topic string) []Template {
+	var own, generic []Template
+	for _, t := range templates {
+		switch {
+		case !t.Enabled:
+		case t.Topic == topic:
+			own = append(own, t)
+		case t.Topic == "":
+			generic = append(generic, t)
+		}
+	}
+	if len(own) > 0 {
+		return own
+	}
+	return generic
+}
+
+// Rotate picks the occurrence-th item (wrapping), so the n-th time a topic
+// is featured uses the next variant rather than repeating the last one.
+func Rotate[T any](items []T, occurrence int) (T, bool) {
+	var zero T
+	if len(items) == 0 {
+		return zero, false
+	}
+	if occurrence < 0 {
+		occurrence = -occurrence
+	}
+	return items[occurrence%len(items)], true
+}
+
+// Check parses body and renders it against a sample payload so editors get
+// template errors when saving rather than when the worker runs.
+func Check(body string) error {
+	in := Input{Date: "2000-01-01", Topic: "sample", Blurb: "A sample blurb."}
+	for _, s := range scripture.LegacySources {
+		in.Passages = append(in.Passages, scripture.Passage{Source: s.ID, Name: s.Name,
+			Ref: "1:1", Text: "Sample text.", Citation: scripture.Cite(s.CitationStyle, "1:1")})
+	}
+	_, err := Render(body, in)
+	return err
+}
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS schedule_overrides (
+    date TEXT PRIMARY KEY,
+    topic TEXT NOT NULL,
+    verse_ids TEXT NOT NULL DEFAULT '',  -- comma-separated verses.id values
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
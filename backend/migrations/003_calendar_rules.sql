@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS calendar_rules (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    calendar TEXT NOT NULL,       -- 'gregorian', 'hijri', 'hebrew', 'easter'
+    month INTEGER NOT NULL DEFAULT 0,
+    day INTEGER NOT NULL DEFAULT 0,
+    offset_days INTEGER NOT NULL DEFAULT 0,
+    duration_days INTEGER NOT NULL DEFAULT 1,
+    topic TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1 @@
+ALTER TABLE subscribers ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS sources (
+    id TEXT PRIMARY KEY,          -- matches verses.source
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    citation_style TEXT NOT NULL DEFAULT '{ref}',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+INSERT INTO sources (id, name, position, citation_style, enabled) VALUES
+    ('quran', 'Qur''an', 10, 'Qur''an {ref}', TRUE),
+    ('torah', 'Torah', 20, '{ref}', TRUE),
+    ('bible', 'Bible', 30, '{ref}', TRUE),
+    ('human_design', 'Human Design', 40, '{ref}', TRUE)
+ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,20 @@
+ALTER TABLE daily_payloads ALTER COLUMN payload_json TYPE JSONB USING payload_json::jsonb;
+ALTER TABLE daily_payloads ADD COLUMN IF NOT EXISTS schema_version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE daily_payloads ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS daily_payload_revisions (
+    id SERIAL PRIMARY KEY,
+    date TEXT NOT NULL,
+    revision INTEGER NOT NULL,
+    schema_version INTEGER NOT NULL,
+    payload_json JSONB NOT NULL,
+    author TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (date, revision)
+);
+
+INSERT INTO daily_payload_revisions (date, revision, schema_version, payload_json, author, reason, created_at)
+SELECT p.date, 1, p.schema_version, p.payload_json, 'migration', 'existing payload', p.created_at
+FROM daily_payloads p
+WHERE NOT EXISTS (SELECT 1 FROM daily_payload_revisions r WHERE r.date = p.date);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS summary_templates (
+    id SERIAL PRIMARY KEY,
+    topic TEXT NOT NULL DEFAULT '',   -- '' applies to every topic
+    body TEXT NOT NULL,               -- Go text/template, see internal/summary
+    enabled BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS topic_blurbs (
+    id SERIAL PRIMARY KEY,
+    topic TEXT NOT NULL,
+    body TEXT NOT NULL
+);
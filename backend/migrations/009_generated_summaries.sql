@@ -0,0 +1,10 @@
+-- Cache of summaries written by an external provider, so re-running the
+-- worker for a date does not call the provider again.
+CREATE TABLE IF NOT EXISTS generated_summaries (
+    date TEXT NOT NULL,
+    input_hash TEXT NOT NULL,         -- provider + topic + passages
+    provider TEXT NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (date, input_hash)
+);
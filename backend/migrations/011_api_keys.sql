@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    role TEXT NOT NULL,               -- reader, editor, sender, admin
+    prefix TEXT NOT NULL UNIQUE,      -- public part of the key, for lookup
+    key_hash TEXT NOT NULL,           -- SHA-256 of the whole key
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1,15 @@
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS sources TEXT NOT NULL DEFAULT '',          -- comma-separated; '' means all
+    ADD COLUMN IF NOT EXISTS frequency TEXT NOT NULL DEFAULT 'daily',   -- daily, weekdays, weekly
+    ADD COLUMN IF NOT EXISTS digest_weekday INTEGER NOT NULL DEFAULT 0, -- 0 = Sunday
+    ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en',
+    ADD COLUMN IF NOT EXISTS delivery_hour INTEGER;                      -- NULL uses DELIVERY_HOUR
+
+-- Verse text in other languages, matched to passages by source and ref.
+CREATE TABLE IF NOT EXISTS verse_translations (
+    source TEXT NOT NULL,
+    ref TEXT NOT NULL,
+    language TEXT NOT NULL,
+    text TEXT NOT NULL,
+    PRIMARY KEY (source, ref, language)
+);
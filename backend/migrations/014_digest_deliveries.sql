@@ -0,0 +1,10 @@
+-- One row per weekly digest sent, keyed by the last date it covers.
+CREATE TABLE IF NOT EXISTS digest_deliveries (
+    week_ending TEXT NOT NULL,
+    subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,         -- 'pending', 'sent', 'failed'
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMPTZ,
+    PRIMARY KEY (week_ending, subscriber_id)
+);
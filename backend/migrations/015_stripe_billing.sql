@@ -0,0 +1,11 @@
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS stripe_customer_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS stripe_subscription_id TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS billing_status TEXT NOT NULL DEFAULT 'none'; -- none, active, past_due, canceled
+
+-- Webhook events already handled, so redelivered events are ignored.
+CREATE TABLE IF NOT EXISTS stripe_events (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    received_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
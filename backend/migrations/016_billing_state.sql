@@ -0,0 +1,5 @@
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS billing_period_end TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS past_due_since TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS comped BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS comp_until TIMESTAMPTZ; -- NULL with comped means indefinitely
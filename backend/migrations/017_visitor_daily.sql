@@ -0,0 +1,25 @@
+-- Per-day, per-endpoint view counts. visitor_stats keeps the all-time
+-- total from before these existed and is no longer incremented.
+CREATE TABLE IF NOT EXISTS visitor_daily (
+    day TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    uniques INTEGER,              -- filled in when the day is compacted
+    PRIMARY KEY (day, endpoint)
+);
+
+-- Distinct visitors of the current day, as salted hashes. Rows are counted
+-- into visitor_daily.uniques and deleted once the day is over.
+CREATE TABLE IF NOT EXISTS visitor_uniques (
+    day TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    visitor TEXT NOT NULL,        -- salted hash of IP and User-Agent
+    PRIMARY KEY (day, endpoint, visitor)
+);
+
+-- One random salt per day, deleted with the day's hashes so they cannot be
+-- linked across days.
+CREATE TABLE IF NOT EXISTS visitor_salts (
+    day TEXT PRIMARY KEY,
+    salt TEXT NOT NULL
+);
@@ -0,0 +1,31 @@
+-- Opting out of open and click tracking.
+ALTER TABLE subscribers ADD COLUMN IF NOT EXISTS no_tracking BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Daily emails sent with tracking. The token identifies the email in the
+-- open pixel and link URLs.
+CREATE TABLE IF NOT EXISTS email_messages (
+    token TEXT PRIMARY KEY,       -- random, in the pixel and link URLs
+    date TEXT NOT NULL,
+    subscriber_id INTEGER NOT NULL REFERENCES subscribers(id) ON DELETE CASCADE,
+    topic TEXT NOT NULL,
+    sent_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS email_messages_date_idx ON email_messages (date);
+
+-- Where each tracked link of an email redirects to.
+CREATE TABLE IF NOT EXISTS email_links (
+    token TEXT NOT NULL REFERENCES email_messages(token) ON DELETE CASCADE,
+    n INTEGER NOT NULL,
+    url TEXT NOT NULL,
+    PRIMARY KEY (token, n)
+);
+
+-- Opens and clicks. No IP address or User-Agent is kept.
+CREATE TABLE IF NOT EXISTS email_events (
+    id SERIAL PRIMARY KEY,
+    token TEXT NOT NULL REFERENCES email_messages(token) ON DELETE CASCADE,
+    kind TEXT NOT NULL,           -- 'open' or 'click'
+    link INTEGER,                 -- the clicked link's n
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS email_events_token_idx ON email_events (token);
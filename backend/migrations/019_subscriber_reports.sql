@@ -0,0 +1,20 @@
+-- When the subscriber first used a sign-in link, proving the address.
+ALTER TABLE subscribers ADD COLUMN IF NOT EXISTS confirmed_at TIMESTAMPTZ;
+
+UPDATE subscribers s SET confirmed_at = t.first_used
+FROM (SELECT subscriber_id, MIN(used_at) AS first_used FROM login_tokens WHERE used_at IS NOT NULL GROUP BY subscriber_id) t
+WHERE s.id = t.subscriber_id AND s.confirmed_at IS NULL;
+
+-- deliveries.status and digest_deliveries.status may now also be 'bounced':
+-- the mail server permanently rejected the address.
+
+-- One row per worker delivery run that sent anything.
+CREATE TABLE IF NOT EXISTS delivery_runs (
+    id SERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,           -- 'daily' or 'digest'
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ NOT NULL,
+    sent INTEGER NOT NULL,
+    failed INTEGER NOT NULL,
+    bounced INTEGER NOT NULL
+);
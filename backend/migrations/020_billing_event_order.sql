@@ -0,0 +1,2 @@
+ALTER TABLE subscribers
+    ADD COLUMN IF NOT EXISTS billing_event_at TIMESTAMPTZ; -- creation time of the last payment event applied
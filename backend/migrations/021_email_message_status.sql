@@ -0,0 +1,4 @@
+-- Tracked emails are now stored before they are sent, and marked with the
+-- delivery's status afterwards. Earlier rows were only stored once sent.
+ALTER TABLE email_messages
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'sent'; -- sending, sent, failed or bounced
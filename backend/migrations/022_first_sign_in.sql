@@ -0,0 +1,3 @@
+-- A first sign-in does not confirm a subscription: subscribers are not
+-- asked to sign in, so most never do. Name the column for what it records.
+ALTER TABLE subscribers RENAME COLUMN confirmed_at TO first_sign_in_at;
@@ -1,11 +1,56 @@
-
 export type Passage = { ref: string; text: string; translation?: string }
+export type SourcePassage = { source: string; name: string; ref: string; text: string; citation: string }
 export type Daily = {
   date: string
   area: string
+  passages?: SourcePassage[]
   quran: Passage
   torah: Passage
   bible: Passage
   human_design: { ref: string; text: string }
   summary: string
 }
+
+// passagesOf returns the payload's passages in display order, falling back
+// to the fixed fields for payloads served by older backends.
+export function passagesOf(d: Daily): SourcePassage[] {
+  if (d.passages && d.passages.length > 0) return d.passages
+  const legacy: [string, string, Passage | undefined][] = [
+    ['quran', "Qur'an", d.quran],
+    ['torah', 'Torah', d.torah],
+    ['bible', 'Bible', d.bible],
+    ['human_design', 'Human Design', d.human_design],
+  ]
+  return legacy
+    .filter(([, , p]) => p)
+    .map(([source, name, p]) => ({ source, name, ref: p!.ref, text: p!.text, citation: p!.ref }))
+}
+
+// apiURL prefixes an API path with VITE_API_BASE_URL, which is set when the
+// API is served from another origin than the web app. Requests that rely on
+// the session cookie must also pass credentials: 'include'.
+export function apiURL(path: string): string {
+  return (import.meta.env.VITE_API_BASE_URL ?? '').replace(/\/$/, '') + path
+}
+
+// Account is the signed-in subscriber, as returned by GET /api/me.
+export type Account = {
+  id: number
+  full_name: string
+  email: string
+  phone: string
+  address: string
+  city: string
+  country: string
+  timezone: string
+  preferences: {
+    sources: string[]
+    frequency: string
+    digest_weekday: number
+    language: string
+    delivery_hour: number | null
+    no_tracking: boolean
+  }
+  billing: { status: string; period_end?: string; comped: boolean }
+  unsubscribed_at?: string
+}
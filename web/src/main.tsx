@@ -6,7 +6,9 @@ import Landing from './pages/Landing'
 import Scriptures from './pages/Scriptures'
 import Post from './pages/Post'
 import Subscribe from './pages/Subscribe'
-import Payment from './pages/Payment'
+import PaymentSuccess from './pages/PaymentSuccess'
+import Login from './pages/Login'
+import Account from './pages/Account'
 import './styles.css'
 
 const router = createBrowserRouter([
@@ -14,7 +16,9 @@ const router = createBrowserRouter([
   { path: '/scriptures', element: <Scriptures /> },
   { path: '/post/:date', element: <Post /> },
   { path: '/subscribe', element: <Subscribe /> },
-  { path: '/payment', element: <Payment /> }
+  { path: '/payment-success', element: <PaymentSuccess /> },
+  { path: '/login', element: <Login /> },
+  { path: '/account', element: <Account /> }
 ])
 
 ReactDOM.createRoot(document.getElementById('root')!).render(
@@ -0,0 +1,202 @@
+import { useEffect, useState } from 'react'
+import { useNavigate } from 'react-router-dom'
+import { Account as AccountData, apiURL } from '../lib'
+
+const fields: [keyof AccountData & string, string, string][] = [
+  ['full_name', 'fullName', 'Full Name'],
+  ['phone', 'phone', 'Phone Number'],
+  ['country', 'country', 'Country'],
+  ['city', 'city', 'City'],
+  ['address', 'address', 'Address'],
+  ['timezone', 'timezone', 'Time Zone'],
+]
+
+// Account lets a signed-in subscriber edit their details and preferences,
+// pause or resume emails, sign out or delete their account. The session
+// cookie is set by the sign-in link; without it the page sends the visitor
+// to /login.
+export default function Account() {
+  const navigate = useNavigate()
+  const [account, setAccount] = useState<AccountData | null>(null)
+  const [form, setForm] = useState<Record<string, string>>({})
+  const [message, setMessage] = useState<string | null>(null)
+  const [error, setError] = useState<string | null>(null)
+
+  const load = (a: AccountData) => {
+    setAccount(a)
+    setForm(Object.fromEntries(fields.map(([key, name]) => [name, String(a[key] ?? '')])))
+  }
+
+  useEffect(() => {
+    fetch(apiURL('/api/me'), { credentials: 'include' })
+      .then(r => {
+        if (r.status === 401) {
+          navigate('/login')
+          return null
+        }
+        if (!r.ok) throw new Error(String(r.status))
+        return r.json()
+      })
+      .then(a => a && load(a))
+      .catch(() => setError('Could not load your account.'))
+  }, [navigate])
+
+  const update = async (body: object, done: string) => {
+    setMessage(null)
+    setError(null)
+    try {
+      const response = await fetch(apiURL('/api/me'), {
+        method: 'PUT',
+        credentials: 'include',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(body),
+      })
+      const result = await response.json()
+      if (!response.ok) throw new Error(result.error)
+      load(result)
+      setMessage(done)
+    } catch (e) {
+      setError(e instanceof Error && e.message === 'bad_timezone' ? 'That time zone is not recognised.' : 'Could not save your changes.')
+    }
+  }
+
+  const handleLogout = async () => {
+    await fetch(apiURL('/api/logout'), { method: 'POST', credentials: 'include' }).catch(() => null)
+    navigate('/')
+  }
+
+  const handleDelete = async () => {
+    if (!confirm('Delete your account? This cannot be undone.')) return
+    const response = await fetch(apiURL('/api/me'), { method: 'DELETE', credentials: 'include' }).catch(() => null)
+    if (response?.ok) navigate('/')
+    else setError('Could not delete your account.')
+  }
+
+  if (!account) {
+    return (
+      <div className="container py-6 max-w-2xl">
+        {error ? <div className="p-3 bg-amber-50 border border-amber-200 rounded">{error}</div> : <p className="text-gray-600">Loading…</p>}
+      </div>
+    )
+  }
+
+  const prefs = account.preferences
+  const subscribed = !account.unsubscribed_at
+
+  return (
+    <div className="container py-6 max-w-2xl">
+      <header className="mb-8 flex justify-between items-start">
+        <div>
+          <a href="/" className="text-blue-600 hover:text-blue-700 text-sm mb-2 inline-block">
+            ← Back to Home
+          </a>
+          <h1 className="text-3xl font-bold">Your Account</h1>
+          <p className="text-gray-600 mt-2">{account.email}</p>
+        </div>
+        <button onClick={handleLogout} className="text-sm text-blue-600 hover:underline">
+          Sign out
+        </button>
+      </header>
+
+      {message && <div className="mb-4 p-3 bg-green-50 border border-green-200 rounded">{message}</div>}
+      {error && <div className="mb-4 p-3 bg-amber-50 border border-amber-200 rounded">{error}</div>}
+
+      <section className="card mb-6">
+        <div className="flex justify-between items-center">
+          <div>
+            <h2 className="text-xl font-semibold">Emails</h2>
+            <p className="text-sm text-gray-600">
+              {subscribed ? 'You are receiving Scripture Daily.' : 'Your emails are paused.'}
+              {account.billing.status && ` Billing: ${account.billing.status}.`}
+            </p>
+          </div>
+          <button
+            onClick={() => update({ subscribed: !subscribed }, subscribed ? 'Your emails are paused.' : 'Welcome back! Your emails will resume.')}
+            className="px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors font-semibold text-sm"
+          >
+            {subscribed ? 'Pause emails' : 'Resubscribe'}
+          </button>
+        </div>
+      </section>
+
+      <section className="card mb-6">
+        <h2 className="text-xl font-semibold mb-4">Your Details</h2>
+        <div className="grid gap-4 md:grid-cols-2">
+          {fields.map(([, name, label]) => (
+            <div key={name}>
+              <label className="block text-sm font-medium text-gray-700 mb-1">{label}</label>
+              <input
+                type="text"
+                value={form[name] ?? ''}
+                onChange={e => setForm(prev => ({ ...prev, [name]: e.target.value }))}
+                className="w-full px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-blue-500 outline-none"
+              />
+            </div>
+          ))}
+        </div>
+        <button
+          onClick={() => update(form, 'Your details were saved.')}
+          className="mt-4 px-4 py-2 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors font-semibold text-sm"
+        >
+          Save details
+        </button>
+      </section>
+
+      <section className="card mb-6">
+        <h2 className="text-xl font-semibold mb-4">Delivery</h2>
+        <div className="grid gap-4 md:grid-cols-2">
+          <div>
+            <label className="block text-sm font-medium text-gray-700 mb-1">Frequency</label>
+            <select
+              value={prefs.frequency}
+              onChange={e => update({ preferences: { ...prefs, frequency: e.target.value } }, 'Your preferences were saved.')}
+              className="w-full px-3 py-2 border border-gray-300 rounded-lg"
+            >
+              <option value="daily">Every day</option>
+              <option value="weekdays">Weekdays</option>
+              <option value="weekly">Weekly digest</option>
+            </select>
+          </div>
+          <div>
+            <label className="block text-sm font-medium text-gray-700 mb-1">Delivery hour</label>
+            <select
+              value={prefs.delivery_hour ?? ''}
+              onChange={e =>
+                update(
+                  { preferences: { ...prefs, delivery_hour: e.target.value === '' ? null : Number(e.target.value) } },
+                  'Your preferences were saved.'
+                )
+              }
+              className="w-full px-3 py-2 border border-gray-300 rounded-lg"
+            >
+              <option value="">Default</option>
+              {Array.from({ length: 24 }, (_, h) => (
+                <option key={h} value={h}>{`${String(h).padStart(2, '0')}:00`}</option>
+              ))}
+            </select>
+          </div>
+        </div>
+        <label className="flex items-center gap-3 mt-4 cursor-pointer">
+          <input
+            type="checkbox"
+            checked={prefs.no_tracking}
+            onChange={e => update({ preferences: { ...prefs, no_tracking: e.target.checked } }, 'Your preferences were saved.')}
+            className="w-4 h-4"
+          />
+          <span className="text-sm text-gray-700">Don't track when I open emails or click their links</span>
+        </label>
+      </section>
+
+      <section className="card border-red-200">
+        <h2 className="text-xl font-semibold mb-2">Delete Account</h2>
+        <p className="text-sm text-gray-600 mb-4">Removes your details and stops all emails.</p>
+        <button
+          onClick={handleDelete}
+          className="px-4 py-2 bg-red-600 text-white rounded-lg hover:bg-red-700 transition-colors font-semibold text-sm"
+        >
+          Delete my account
+        </button>
+      </section>
+    </div>
+  )
+}
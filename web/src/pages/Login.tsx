@@ -0,0 +1,78 @@
+import { useState } from 'react'
+import { useSearchParams } from 'react-router-dom'
+import { apiURL } from '../lib'
+
+// Login asks for a one-time sign-in link. The link in the email goes to the
+// API, which starts a session and redirects to /account, or back here with
+// ?error=invalid_link if it was used or expired.
+export default function Login() {
+  const [params] = useSearchParams()
+  const [email, setEmail] = useState('')
+  const [message, setMessage] = useState<string | null>(null)
+  const [error, setError] = useState<string | null>(
+    params.get('error') === 'invalid_link' ? 'That sign-in link has expired or was already used. Request a new one below.' : null
+  )
+  const [sending, setSending] = useState(false)
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    setSending(true)
+    setError(null)
+    try {
+      const response = await fetch(apiURL('/api/login'), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ email }),
+      })
+      const result = await response.json()
+      if (!response.ok) throw new Error(result.error)
+      setMessage(result.message)
+    } catch {
+      setError('Could not send a sign-in link. Please try again.')
+    } finally {
+      setSending(false)
+    }
+  }
+
+  return (
+    <div className="container py-6 max-w-md">
+      <header className="mb-8">
+        <a href="/" className="text-blue-600 hover:text-blue-700 text-sm mb-2 inline-block">
+          ← Back to Home
+        </a>
+        <h1 className="text-3xl font-bold">Sign in</h1>
+        <p className="text-gray-600 mt-2">We'll email you a link to manage your subscription.</p>
+      </header>
+
+      {error && <div className="mb-4 p-3 bg-amber-50 border border-amber-200 rounded">{error}</div>}
+
+      {message ? (
+        <div className="card">
+          <p>{message}</p>
+          <p className="text-sm text-gray-600 mt-2">The link works once and expires after 15 minutes.</p>
+        </div>
+      ) : (
+        <form onSubmit={handleSubmit} className="card space-y-4">
+          <div>
+            <label className="block text-sm font-medium text-gray-700 mb-1">Email Address</label>
+            <input
+              type="email"
+              value={email}
+              onChange={e => setEmail(e.target.value)}
+              className="w-full px-3 py-2 border border-gray-300 rounded-lg focus:ring-2 focus:ring-blue-500 focus:border-blue-500 outline-none"
+              placeholder="john@example.com"
+              required
+            />
+          </div>
+          <button
+            type="submit"
+            disabled={sending}
+            className="w-full px-6 py-3 bg-blue-600 text-white rounded-lg hover:bg-blue-700 transition-colors font-semibold disabled:bg-gray-400"
+          >
+            {sending ? 'Sending…' : 'Email me a sign-in link'}
+          </button>
+        </form>
+      )}
+    </div>
+  )
+}
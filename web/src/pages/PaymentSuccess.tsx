@@ -0,0 +1,24 @@
+// PaymentSuccess is where checkout returns after paying. The subscription
+// is activated by the payment provider's webhook, not by this page, which
+// only thanks the subscriber; the welcome email follows the webhook.
+export default function PaymentSuccess() {
+  return (
+    <div className="container py-6 max-w-md">
+      <div className="card text-center">
+        <h1 className="text-2xl font-bold mb-2">Payment received</h1>
+        <p className="text-gray-600 mb-6">
+          Thank you for subscribing to Scripture Daily. A welcome email is on its way, and your daily scriptures will
+          follow.
+        </p>
+        <div className="space-y-2">
+          <a href="/login" className="block text-blue-600 hover:underline">
+            Sign in to manage your subscription
+          </a>
+          <a href="/" className="block text-blue-600 hover:underline">
+            Return to Home
+          </a>
+        </div>
+      </div>
+    </div>
+  )
+}
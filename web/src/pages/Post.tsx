@@ -1,17 +1,7 @@
 
 import React, { useEffect, useState } from 'react'
 import { useParams, Link } from 'react-router-dom'
-
-type Passage = { ref: string; text: string; translation?: string }
-type Daily = {
-  date: string
-  area: string
-  quran: Passage
-  torah: Passage
-  bible: Passage
-  human_design: { ref: string; text: string }
-  summary: string
-}
+import { Daily, passagesOf } from '../lib'
 
 export default function Post() {
   const { date } = useParams()
@@ -39,10 +29,9 @@ export default function Post() {
           </div>
 
           <div className="grid gap-4 md:grid-cols-2">
-            <div className="card"><h3 className="font-semibold">Qur'an</h3><p><em>{data.quran?.ref}</em></p><p>{data.quran?.text}</p></div>
-            <div className="card"><h3 className="font-semibold">Torah</h3><p><em>{data.torah?.ref}</em></p><p>{data.torah?.text}</p></div>
-            <div className="card"><h3 className="font-semibold">Bible</h3><p><em>{data.bible?.ref}</em></p><p>{data.bible?.text}</p></div>
-            <div className="card"><h3 className="font-semibold">Human Design</h3><p><em>{data.human_design?.ref}</em></p><p>{data.human_design?.text}</p></div>
+            {passagesOf(data).map(p => (
+              <div key={p.source} className="card"><h3 className="font-semibold">{p.name}</h3><p><em>{p.ref}</em></p><p>{p.text}</p></div>
+            ))}
           </div>
 
           <div className="mt-6 p-4 bg-amber-50 border border-amber-200 rounded">
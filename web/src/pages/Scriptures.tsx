@@ -1,16 +1,6 @@
 
 import { useEffect, useState } from 'react'
-
-type Passage = { ref: string; text: string; translation?: string }
-type Daily = {
-  date: string
-  area: string
-  quran: Passage
-  torah: Passage
-  bible: Passage
-  human_design: { ref: string; text: string }
-  summary: string
-}
+import { Daily, passagesOf } from '../lib'
 
 export default function Scriptures() {
   const [data, setData] = useState<Daily | null>(null)
@@ -20,7 +10,8 @@ export default function Scriptures() {
   const [userLocation, setUserLocation] = useState<string>("Loading...")
 
   useEffect(() => {
-    fetch('/api/today').then(r => r.json()).then(setData).catch(() => setErr('Not ready'))
+    const tz = Intl.DateTimeFormat().resolvedOptions().timeZone
+    fetch(`/api/today?tz=${encodeURIComponent(tz)}`).then(r => r.json()).then(setData).catch(() => setErr('Not ready'))
     fetch('/api/visitors').then(r => r.json()).then(d => setVisitorCount(d.count)).catch(() => setVisitorCount(null))
     setUserDate(new Date().toLocaleString())
     if (navigator.geolocation) {
@@ -72,10 +63,9 @@ export default function Scriptures() {
           </div>
 
           <div className="grid gap-4 md:grid-cols-2">
-            <div className="card"><h3 className="font-semibold">Qur'an</h3><p><em>{data.quran?.ref}</em></p><p>{data.quran?.text}</p></div>
-            <div className="card"><h3 className="font-semibold">Torah</h3><p><em>{data.torah?.ref}</em></p><p>{data.torah?.text}</p></div>
-            <div className="card"><h3 className="font-semibold">Bible</h3><p><em>{data.bible?.ref}</em></p><p>{data.bible?.text}</p></div>
-            <div className="card"><h3 className="font-semibold">Human Design</h3><p><em>{data.human_design?.ref}</em></p><p>{data.human_design?.text}</p></div>
+            {passagesOf(data).map(p => (
+              <div key={p.source} className="card"><h3 className="font-semibold">{p.name}</h3><p><em>{p.ref}</em></p><p>{p.text}</p></div>
+            ))}
           </div>
 
           <div className="mt-6 p-4 bg-amber-50 border border-amber-200 rounded">
@@ -87,10 +77,9 @@ export default function Scriptures() {
             <h4 className="font-semibold mb-3">Copyable Summary</h4>
             <div className="space-y-2 text-sm font-mono">
               <div><strong>Verse:</strong></div>
-              <div className="ml-4">• <strong>Quran ({data.quran?.ref}):</strong> {data.quran?.text}</div>
-              <div className="ml-4">• <strong>Torah ({data.torah?.ref}):</strong> {data.torah?.text}</div>
-              <div className="ml-4">• <strong>Bible ({data.bible?.ref}):</strong> {data.bible?.text}</div>
-              <div className="ml-4">• <strong>Human Design ({data.human_design?.ref}):</strong> {data.human_design?.text}</div>
+              {passagesOf(data).map(p => (
+                <div key={p.source} className="ml-4">• <strong>{p.name} ({p.ref}):</strong> {p.text}</div>
+              ))}
               <div><strong>Result:</strong> {data.area}</div>
               <div><strong>Common Ground:</strong> {data.summary}</div>
             </div>
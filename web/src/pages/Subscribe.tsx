@@ -1,4 +1,6 @@
 import { useState } from 'react'
+import { useSearchParams } from 'react-router-dom'
+import { apiURL } from '../lib'
 
 export default function Subscribe() {
   const [formData, setFormData] = useState({
@@ -10,13 +12,23 @@ export default function Subscribe() {
     country: '',
   })
   const [agreedToTerms, setAgreedToTerms] = useState(false)
+  const [params] = useSearchParams()
+  const [submitting, setSubmitting] = useState(false)
+  const [message, setMessage] = useState<string | null>(null)
+  const [error, setError] = useState<string | null>(
+    params.get('canceled') === 'true' ? 'Payment was canceled and you have not been charged. You can try again below.' : null
+  )
 
   const handleInputChange = (e: React.ChangeEvent<HTMLInputElement | HTMLTextAreaElement>) => {
     const { name, value } = e.target
     setFormData(prev => ({ ...prev, [name]: value }))
   }
 
-  const handleStripePayment = () => {
+  // Signing up creates the subscriber. When billing is required the API
+  // answers with the provider's checkoutUrl, which returns to
+  // /payment-success once paid or back here with ?canceled=true; otherwise
+  // the welcome email is already on its way.
+  const handleSubscribe = async () => {
     if (!agreedToTerms) {
       alert('Please accept the Terms and Conditions before proceeding.')
       return
@@ -27,26 +39,28 @@ export default function Subscribe() {
       return
     }
 
-    // Store user data in localStorage for retrieval after payment
-    localStorage.setItem('subscriptionUserData', JSON.stringify(formData))
-
-    console.log('User Data:', formData)
-    console.log('Agreed to Terms:', agreedToTerms)
-
-    // For local development, redirect to local payment page
-    // In production, this will be: https://buy.stripe.com/dRm9ATbGh2fmfTG8ww
-    const isProduction = window.location.hostname !== 'localhost'
-    const STRIPE_CHECKOUT_URL = 'https://buy.stripe.com/dRm9ATbGh2fmfTG8ww'
-
-    if (isProduction) {
-      // Production: Redirect to Stripe
-      console.log('Redirecting to Stripe checkout...')
-      window.location.href = STRIPE_CHECKOUT_URL
-    } else {
-      // Local development: Use local payment page
-      console.log('Using local payment page for development...')
-      window.location.href = '/payment'
+    setSubmitting(true)
+    setError(null)
+    try {
+      const response = await fetch(apiURL('/api/subscribe/email'), {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({
+          ...formData,
+          timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+        }),
+      })
+      const result = await response.json()
+      if (!response.ok) throw new Error(result.error)
+      if (result.checkoutUrl) {
+        window.location.href = result.checkoutUrl
+        return
+      }
+      setMessage(result.message)
+    } catch {
+      setError('Could not start your subscription. Please try again.')
     }
+    setSubmitting(false)
   }
 
   return (
@@ -59,6 +73,9 @@ export default function Subscribe() {
         <p className="text-gray-600 mt-2">Get daily inspiration delivered to your inbox</p>
       </header>
 
+      {error && <div className="mb-4 p-3 bg-amber-50 border border-amber-200 rounded">{error}</div>}
+      {message && <div className="mb-4 p-3 bg-green-50 border border-green-200 rounded">{message}</div>}
+
       {/* User Information Section */}
       <section className="mb-8 bg-white border border-gray-200 rounded-lg p-6">
         <h2 className="text-xl font-semibold mb-4">User Information</h2>
@@ -215,15 +232,15 @@ export default function Subscribe() {
             </p>
           </div>
           <button
-            onClick={handleStripePayment}
-            disabled={!agreedToTerms}
+            onClick={handleSubscribe}
+            disabled={!agreedToTerms || submitting}
             className={`px-8 py-3 rounded-lg font-semibold text-white transition-all ${
-              agreedToTerms
+              agreedToTerms && !submitting
                 ? 'bg-blue-600 hover:bg-blue-700 hover:shadow-lg'
                 : 'bg-gray-400 cursor-not-allowed'
             }`}
           >
-            Proceed to Payment
+            {submitting ? 'Starting checkout…' : 'Proceed to Payment'}
           </button>
         </div>
         {!agreedToTerms && (